@@ -1,5 +1,5 @@
 module github.com/axw/gocov
 
-go 1.12
+go 1.20
 
 require golang.org/x/tools v0.0.0-20190617190820-da514acc4774
@@ -29,6 +29,7 @@ import (
 	"os"
 	"regexp"
 	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/axw/gocov"
@@ -47,11 +48,61 @@ var (
 	annotateCeilingFlag = annotateFlags.Float64(
 		"ceiling", 101,
 		"Annotate only functions whose coverage is less than the specified percentage")
-	annotateColorFlag = annotateFlags.Bool(
-		"color", false,
-		"Differentiate coverage with color")
+	annotateColorFlag colorFlag
 )
 
+func init() {
+	annotateFlags.Var(&annotateColorFlag, "color",
+		`Differentiate coverage with color: "always", "never", or "auto" to do so only when writing to a terminal`)
+}
+
+// colorFlag is the value of annotate's -color flag: "auto", "always"
+// or "never". As the flag was once boolean, -color alone and boolean
+// values are also accepted, as "always" if true and "never" if false.
+type colorFlag struct {
+	mode string
+}
+
+func (f *colorFlag) String() string {
+	if f.mode == "" {
+		return "auto"
+	}
+	return f.mode
+}
+
+func (f *colorFlag) Set(value string) error {
+	switch value {
+	case "auto", "always", "never":
+		f.mode = value
+		return nil
+	}
+	enabled, err := strconv.ParseBool(value)
+	if err != nil {
+		return fmt.Errorf(`expected "auto", "always" or "never"`)
+	}
+	f.mode = "never"
+	if enabled {
+		f.mode = "always"
+	}
+	return nil
+}
+
+func (f *colorFlag) IsBoolFlag() bool {
+	return true
+}
+
+// enabled reports whether output written to file is colored.
+func (f *colorFlag) enabled(file *os.File) bool {
+	switch f.String() {
+	case "always":
+		return true
+	case "never":
+		return false
+	}
+	info, err := file.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
 type packageList []*gocov.Package
 type functionList []*gocov.Function
 
@@ -82,6 +133,10 @@ func (l functionList) Swap(i, j int) {
 type annotator struct {
 	fset  *token.FileSet
 	files map[string]*token.File
+
+	// color determines whether missed lines are marked by color
+	// rather than by missPrefix.
+	color bool
 }
 
 func percentReached(fn *gocov.Function) float64 {
@@ -98,6 +153,10 @@ func percentReached(fn *gocov.Function) float64 {
 }
 
 func annotateSource() (rc int) {
+	if err := applyConfig(annotateFlags); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to apply configuration: %s\n", err)
+		return 1
+	}
 	annotateFlags.Parse(os.Args[2:])
 	if annotateFlags.NArg() == 0 {
 		fmt.Fprintf(os.Stderr, "missing coverage file\n")
@@ -132,6 +191,7 @@ func annotateSource() (rc int) {
 	a := &annotator{}
 	a.fset = token.NewFileSet()
 	a.files = make(map[string]*token.File)
+	a.color = annotateColorFlag.enabled(os.Stdout)
 
 	var regexps []*regexp.Regexp
 	for _, arg := range annotateFlags.Args()[1:] {
@@ -218,7 +278,7 @@ func (a *annotator) printFunctionSource(fn *gocov.Function) error {
 				statements = append(statements[:j], statements[j+1:]...)
 			}
 		}
-		if *annotateColorFlag {
+		if a.color {
 			color := NONE
 			if statementFound && !hit {
 				color = RED
@@ -0,0 +1,81 @@
+// Copyright (c) 2026 The Gocov Authors.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func runAnnotate(t *testing.T, args ...string) (rc int, stdout, stderr string) {
+	defer resetFlags(annotateFlags)
+	defer func(args []string) { os.Args = args }(os.Args)
+	os.Args = append([]string{"gocov", "annotate"}, args...)
+	stdout, stderr = captureOutput(t, func() { rc = annotateSource() })
+	return rc, stdout, stderr
+}
+
+func TestAnnotateColor(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gocov")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	data, err := marshalJson(testPackages(t, "./testdata/simple"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	coverage := filepath.Join(dir, "coverage.json")
+	if err := ioutil.WriteFile(coverage, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// The output is captured in a file rather than a terminal, so it
+	// is only colored if asked for.
+	for _, test := range []struct {
+		args    []string
+		colored bool
+	}{
+		{[]string{coverage}, false},
+		{[]string{"-color=auto", coverage}, false},
+		{[]string{"-color=never", coverage}, false},
+		{[]string{"-color=false", coverage}, false},
+		{[]string{"-color=always", coverage}, true},
+		{[]string{"-color", coverage}, true},
+	} {
+		rc, stdout, stderr := runAnnotate(t, test.args...)
+		if rc != 0 {
+			t.Fatalf("%q: unexpected exit code %d: %s", test.args, rc, stderr)
+		}
+		if colored := strings.Contains(stdout, "\x1b["); colored != test.colored {
+			t.Errorf("%q: got colored %v, expected %v:\n%s", test.args, colored, test.colored, stdout)
+		}
+		if !test.colored && !strings.Contains(stdout, missPrefix) {
+			t.Errorf("%q: expected missed lines to be marked %s:\n%s", test.args, missPrefix, stdout)
+		}
+	}
+	if err := annotateFlags.Set("color", "sometimes"); err == nil {
+		t.Error("expected an error for an invalid -color value")
+	}
+}
@@ -0,0 +1,119 @@
+// Copyright (c) 2026 The Gocov Authors.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"os"
+	"runtime"
+	"runtime/debug"
+	"time"
+)
+
+// testLog records the output of go test while writing an archive
+// with -archive; it is nil otherwise.
+var testLog *bytes.Buffer
+
+// archiveMetadata describes a run of "gocov test", and is stored as
+// metadata.json in the archive written by -archive.
+type archiveMetadata struct {
+	// Command holds the command line of gocov.
+	Command []string
+
+	// Version is the version of gocov, if known.
+	Version string `json:",omitempty"`
+
+	// GoVersion is the version of Go with which gocov was built.
+	GoVersion string
+
+	// Time is the time at which the archive was written.
+	Time time.Time
+
+	// Packages holds the packages that were tested.
+	Packages []string
+
+	// Error describes the failure of the tests, if they failed.
+	Error string `json:",omitempty"`
+}
+
+func newArchiveMetadata(pkgs []string, testErr error) archiveMetadata {
+	m := archiveMetadata{
+		Command:   os.Args,
+		GoVersion: runtime.Version(),
+		Time:      time.Now().UTC(),
+		Packages:  pkgs,
+	}
+	if testErr != nil {
+		m.Error = testErr.Error()
+	}
+	if info, ok := debug.ReadBuildInfo(); ok {
+		m.Version = info.Main.Version
+	}
+	return m
+}
+
+// writeArchive writes a gzipped tar file holding the coverage as
+// coverage.json, the output of go test as test.log, and the metadata
+// as metadata.json.
+func writeArchive(filename string, coverage, log []byte, metadata archiveMetadata) error {
+	metadataJSON, err := json.MarshalIndent(metadata, "", "\t")
+	if err != nil {
+		return err
+	}
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	gz := gzip.NewWriter(f)
+	tw := tar.NewWriter(gz)
+	for _, member := range []struct {
+		name string
+		data []byte
+	}{
+		{"coverage.json", coverage},
+		{"test.log", log},
+		{"metadata.json", metadataJSON},
+	} {
+		hdr := &tar.Header{
+			Name:    member.name,
+			Mode:    0644,
+			Size:    int64(len(member.data)),
+			ModTime: metadata.Time,
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if _, err := tw.Write(member.data); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,139 @@
+// Copyright (c) 2026 The Gocov Authors.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package main
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// readArchive checks the members of the archive written by -archive,
+// and returns their contents.
+func readArchive(t *testing.T, filename string) map[string]string {
+	f, err := os.Open(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	members := make(map[string]string)
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := ioutil.ReadAll(tr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, hdr.Name)
+		members[hdr.Name] = string(data)
+	}
+	if expect := []string{"coverage.json", "test.log", "metadata.json"}; !reflect.DeepEqual(names, expect) {
+		t.Fatalf("got members %v, expected %v", names, expect)
+	}
+	return members
+}
+
+func TestRunTestsArchive(t *testing.T) {
+	defer resetFlags(testFlags)
+	dir, err := ioutil.TempDir("", "gocov")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	archive := filepath.Join(dir, "out.tar.gz")
+	stdout, stderr := captureOutput(t, func() {
+		err = runTests([]string{"-archive", archive, "-v", "./testdata/simple"})
+	})
+	if err != nil {
+		t.Fatalf("%v\n%s", err, stderr)
+	}
+
+	members := readArchive(t, archive)
+	if members["coverage.json"]+"\n" != stdout {
+		t.Errorf("coverage.json does not match the output:\n%s", members["coverage.json"])
+	}
+	if !strings.Contains(members["test.log"], "--- PASS: TestCovered") {
+		t.Errorf("unexpected test.log:\n%s", members["test.log"])
+	}
+	var metadata archiveMetadata
+	if err := json.Unmarshal([]byte(members["metadata.json"]), &metadata); err != nil {
+		t.Fatal(err)
+	}
+	if expect := []string{"github.com/axw/gocov/gocov/testdata/simple"}; !reflect.DeepEqual(metadata.Packages, expect) {
+		t.Errorf("got packages %v, expected %v", metadata.Packages, expect)
+	}
+	if metadata.GoVersion == "" || metadata.Time.IsZero() || len(metadata.Command) == 0 {
+		t.Errorf("incomplete metadata: %+v", metadata)
+	}
+	if metadata.Error != "" {
+		t.Errorf("unexpected error in metadata: %s", metadata.Error)
+	}
+}
+
+func TestRunTestsArchiveFailing(t *testing.T) {
+	defer resetFlags(testFlags)
+	dir, err := ioutil.TempDir("", "gocov")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	archive := filepath.Join(dir, "out.tar.gz")
+	_, stderr := captureOutput(t, func() {
+		err = runTests([]string{"-archive", archive, "./testdata/failing"})
+	})
+	if err == nil {
+		t.Fatalf("expected the tests to fail\n%s", stderr)
+	}
+
+	// The archive is written with the output of the failed tests.
+	members := readArchive(t, archive)
+	if !strings.Contains(members["test.log"], "Broken is broken") {
+		t.Errorf("unexpected test.log:\n%s", members["test.log"])
+	}
+	if _, err := unmarshalJson([]byte(members["coverage.json"])); err != nil {
+		t.Errorf("invalid coverage.json: %v", err)
+	}
+	var metadata archiveMetadata
+	if err := json.Unmarshal([]byte(members["metadata.json"]), &metadata); err != nil {
+		t.Fatal(err)
+	}
+	if metadata.Error != err.Error() {
+		t.Errorf("got error %q in metadata, expected %q", metadata.Error, err)
+	}
+}
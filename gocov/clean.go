@@ -0,0 +1,117 @@
+// Copyright (c) 2026 The Gocov Authors.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+var (
+	cleanFlags      = flag.NewFlagSet("clean", flag.ExitOnError)
+	cleanDryRunFlag = cleanFlags.Bool(
+		"dry-run", false,
+		"List the directories that would be removed, without removing them")
+	cleanOlderThanFlag = cleanFlags.Duration(
+		"older-than", 24*time.Hour,
+		"Only remove directories last modified longer ago than this")
+)
+
+// cleanTemp removes the temporary directories left behind by gocov
+// test and gocov run, such as when gocov was killed.
+func cleanTemp(args []string) error {
+	cleanFlags.Parse(args)
+	if cleanFlags.NArg() > 0 {
+		return fmt.Errorf("unexpected arguments: %s", strings.Join(cleanFlags.Args(), " "))
+	}
+	return cleanTempDirs(os.Stdout, os.TempDir(), time.Now().Add(-*cleanOlderThanFlag), *cleanDryRunFlag)
+}
+
+// cleanTempDirs removes the directories in dir that were created by
+// gocov and last modified before cutoff, printing the name of each.
+// If dryRun is true, the directories are printed but not removed.
+func cleanTempDirs(w io.Writer, dir string, cutoff time.Time, dryRun bool) error {
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, info := range infos {
+		if !info.IsDir() || !strings.HasPrefix(info.Name(), "gocov") || !info.ModTime().Before(cutoff) {
+			continue
+		}
+		path := filepath.Join(dir, info.Name())
+		if !isGocovTempDir(path) {
+			continue
+		}
+		fmt.Fprintln(w, path)
+		if dryRun {
+			continue
+		}
+		if err := os.RemoveAll(path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// isTestRunFile reports whether a file or directory is one that gocov
+// test writes for each package run: a profile named test*.cov, or the
+// test binary and coverage data of -exit-coverage.
+func isTestRunFile(info os.FileInfo) bool {
+	name := info.Name()
+	if !strings.HasPrefix(name, "test") {
+		return false
+	}
+	if info.IsDir() {
+		return strings.HasSuffix(name, ".cover")
+	}
+	return strings.HasSuffix(name, ".cov") || strings.HasSuffix(name, ".test") || strings.HasSuffix(name, ".test.exe")
+}
+
+// isGocovTempDir reports whether the directory holds only what gocov
+// test and gocov run write to their temporary directories: profiles
+// named test*.cov, with the test binaries and coverage data of
+// -exit-coverage, or the program, its profile and the directory of
+// its coverage data. Other directories whose names begin with "gocov"
+// are left alone.
+func isGocovTempDir(dir string) bool {
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return false
+	}
+	for _, info := range infos {
+		name := info.Name()
+		switch {
+		case isTestRunFile(info):
+		case !info.IsDir() && (name == "program" || name == "program.exe" || name == "program.cov"):
+		case info.IsDir() && name == "cover":
+		default:
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,106 @@
+// Copyright (c) 2026 The Gocov Authors.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCleanTempDirs(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gocov")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// Each directory is created with the given files, and last
+	// modified at the given age.
+	dirs := []struct {
+		name   string
+		files  []string
+		age    time.Duration
+		remove bool
+	}{
+		{"gocov123", []string{"test0.cov", "test1.cov"}, 48 * time.Hour, true},
+		{"gocov456", []string{"program", "program.cov", "cover/covmeta.1"}, 48 * time.Hour, true},
+		{"gocov789", nil, 48 * time.Hour, true},
+		{"gocov-recent", []string{"test0.cov"}, time.Minute, false},
+		{"gocov-other", []string{"test0.cov", "notes.txt"}, 48 * time.Hour, false},
+		{"other", []string{"test0.cov"}, 48 * time.Hour, false},
+	}
+	for _, d := range dirs {
+		path := filepath.Join(dir, d.name)
+		for _, file := range d.files {
+			file = filepath.Join(path, file)
+			if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+				t.Fatal(err)
+			}
+			if err := ioutil.WriteFile(file, nil, 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := os.MkdirAll(path, 0755); err != nil {
+			t.Fatal(err)
+		}
+		modified := time.Now().Add(-d.age)
+		if err := os.Chtimes(path, modified, modified); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cutoff := time.Now().Add(-24 * time.Hour)
+
+	// A dry run lists the directories but removes nothing.
+	var dryRun bytes.Buffer
+	if err := cleanTempDirs(&dryRun, dir, cutoff, true); err != nil {
+		t.Fatal(err)
+	}
+	for _, d := range dirs {
+		if _, err := os.Stat(filepath.Join(dir, d.name)); err != nil {
+			t.Errorf("%s removed by a dry run", d.name)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := cleanTempDirs(&buf, dir, cutoff, false); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != dryRun.String() {
+		t.Errorf("dry run listed:\n%s\nbut removed:\n%s", dryRun.String(), buf.String())
+	}
+	var expect string
+	for _, d := range dirs {
+		_, err := os.Stat(filepath.Join(dir, d.name))
+		if removed := os.IsNotExist(err); removed != d.remove {
+			t.Errorf("%s: removed=%v, expected %v", d.name, removed, d.remove)
+		}
+		if d.remove {
+			expect += filepath.Join(dir, d.name) + "\n"
+		}
+	}
+	if buf.String() != expect {
+		t.Errorf("got:\n%s\nexpected:\n%s", buf.String(), expect)
+	}
+}
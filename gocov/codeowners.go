@@ -0,0 +1,152 @@
+// Copyright (c) 2026 The Gocov Authors.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package main
+
+import (
+	"io/ioutil"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// unowned is the owner reported by -by owner for files that match no
+// rule of the CODEOWNERS file, or a rule without owners.
+const unowned = "unowned"
+
+// codeOwnersRule is a line of a CODEOWNERS file: a pattern, and the
+// owners of the files that it matches.
+type codeOwnersRule struct {
+	pattern string
+	owners  []string
+}
+
+// codeOwners maps files to their owners, as given by a CODEOWNERS file.
+type codeOwners struct {
+	// root is the directory to which the patterns are relative:
+	// the repository containing the file.
+	root  string
+	rules []codeOwnersRule
+}
+
+// readCodeOwners reads the named CODEOWNERS file. As GitHub looks for
+// the file in the repository's root, .github and docs directories,
+// patterns in a file within .github or docs are relative to the
+// parent directory.
+func readCodeOwners(filename string) (*codeOwners, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	root, err := filepath.Abs(filepath.Dir(filename))
+	if err != nil {
+		return nil, err
+	}
+	if base := filepath.Base(root); base == ".github" || base == "docs" {
+		root = filepath.Dir(root)
+	}
+	return &codeOwners{root: root, rules: parseCodeOwners(data)}, nil
+}
+
+// parseCodeOwners parses the rules of a CODEOWNERS file, ignoring blank
+// lines and comments.
+func parseCodeOwners(data []byte) []codeOwnersRule {
+	var rules []codeOwnersRule
+	for _, line := range strings.Split(string(data), "\n") {
+		if hash := strings.Index(line, "#"); hash >= 0 {
+			line = line[:hash]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		rules = append(rules, codeOwnersRule{pattern: fields[0], owners: fields[1:]})
+	}
+	return rules
+}
+
+// owner returns the owners of the named file, separated by spaces, or
+// unowned. As in GitHub, the last rule matching the file applies.
+func (c *codeOwners) owner(filename string) string {
+	rel, err := filepath.Rel(c.root, filename)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return unowned
+	}
+	rel = filepath.ToSlash(rel)
+	for i := len(c.rules) - 1; i >= 0; i-- {
+		if matchCodeOwnersPattern(c.rules[i].pattern, rel) {
+			if len(c.rules[i].owners) == 0 {
+				return unowned
+			}
+			return strings.Join(c.rules[i].owners, " ")
+		}
+	}
+	return unowned
+}
+
+// matchCodeOwnersPattern reports whether the gitignore-style pattern
+// of a CODEOWNERS rule matches the file, named by a slash-separated
+// path relative to the repository. A pattern matches a file or any of
+// its parent directories. A pattern starting with, or containing, a
+// slash is relative to the repository, and otherwise matches at any
+// depth; a pattern ending with a slash matches only directories. "*"
+// matches within a path element, and "**" any number of elements.
+func matchCodeOwnersPattern(pattern, file string) bool {
+	dirOnly := strings.HasSuffix(pattern, "/")
+	pattern = strings.TrimSuffix(pattern, "/")
+	anchored := strings.HasPrefix(pattern, "/") || strings.Contains(pattern, "/")
+	pattern = strings.TrimPrefix(pattern, "/")
+	patternElems := strings.Split(pattern, "/")
+	if !anchored {
+		patternElems = append([]string{"**"}, patternElems...)
+	}
+	fileElems := strings.Split(file, "/")
+	for n := len(fileElems); n > 0; n-- {
+		if dirOnly && n == len(fileElems) {
+			continue
+		}
+		if matchElems(patternElems, fileElems[:n]) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchElems reports whether the path elements match the pattern's.
+func matchElems(pattern, elems []string) bool {
+	if len(pattern) == 0 {
+		return len(elems) == 0
+	}
+	if pattern[0] == "**" {
+		for i := 0; i <= len(elems); i++ {
+			if matchElems(pattern[1:], elems[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(elems) == 0 {
+		return false
+	}
+	if ok, err := path.Match(pattern[0], elems[0]); err != nil || !ok {
+		return false
+	}
+	return matchElems(pattern[1:], elems[1:])
+}
@@ -0,0 +1,125 @@
+// Copyright (c) 2026 The Gocov Authors.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/axw/gocov"
+)
+
+func TestMatchCodeOwnersPattern(t *testing.T) {
+	tests := []struct {
+		pattern, file string
+		match         bool
+	}{
+		{"*", "a/b.go", true},
+		{"*.go", "a/b.go", true},
+		{"*.go", "a/b.txt", false},
+		{"/b.go", "b.go", true},
+		{"/b.go", "a/b.go", false},
+		{"b.go", "a/b.go", true},
+		{"a/", "a/b.go", true},
+		{"a/", "x/a/b.go", true},
+		{"a/", "a", false},
+		{"/a/", "x/a/b.go", false},
+		{"a/b", "a/b/c.go", true},
+		{"a/b", "x/a/b/c.go", false},
+		{"a/*.go", "a/b.go", true},
+		{"a/*.go", "a/b/c.go", false},
+		{"a/**/c.go", "a/c.go", true},
+		{"a/**/c.go", "a/b/d/c.go", true},
+		{"**/b/c.go", "a/b/c.go", true},
+	}
+	for _, test := range tests {
+		if match := matchCodeOwnersPattern(test.pattern, test.file); match != test.match {
+			t.Errorf("%q matching %q: got %v, expected %v", test.pattern, test.file, match, test.match)
+		}
+	}
+}
+
+func TestReportByOwner(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gocov")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if err := os.Mkdir(filepath.Join(dir, ".github"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	codeowners := filepath.Join(dir, ".github", "CODEOWNERS")
+	err = ioutil.WriteFile(codeowners, []byte(`# Default owners.
+*           @org/core
+
+/api/       @org/api    # The API team owns the API...
+/api/v1/    @org/legacy @alice
+/api/gen.go
+`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	owners, err := readCodeOwners(codeowners)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	file := func(name string) string { return filepath.Join(dir, name) }
+	r := newTestReport(
+		&gocov.Package{Name: "example.com/api", Functions: []*gocov.Function{
+			newFunction("Get", file("api/get.go"), 1, 1, 0),
+			newFunction("gen", file("api/gen.go"), 0, 0),
+		}},
+		&gocov.Package{Name: "example.com/api/v1", Functions: []*gocov.Function{
+			newFunction("Get", file("api/v1/get.go"), 1, 0),
+		}},
+		&gocov.Package{Name: "example.com/util", Functions: []*gocov.Function{
+			newFunction("Trim", file("util/trim.go"), 1),
+			newFunction("Other", "/elsewhere/other.go", 0),
+		}},
+	)
+	r.by = "owner"
+	r.owners = owners
+
+	var buf bytes.Buffer
+	printReport(&buf, r)
+	// The columns are padded with tabs.
+	output := regexp.MustCompile("\t+").ReplaceAllString(buf.String(), "\t")
+	for _, line := range []string{
+		"@org/api\t 66.67% (2/3)\n",
+		"@org/core\t 100.00% (1/1)\n",
+		"@org/legacy @alice\t 50.00% (1/2)\n",
+		"unowned\t 0.00% (0/3)\n",
+		"Total Coverage: 44.44% (4/9)\n",
+	} {
+		if !strings.Contains(output, line) {
+			t.Errorf("expected %q in output:\n%s", line, buf.String())
+		}
+	}
+	if report := newJSONReport(r); len(report.Owners) != 4 || report.Owners[3].Name != unowned {
+		t.Errorf("unexpected owners in JSON report: %+v", report.Owners)
+	}
+}
@@ -0,0 +1,277 @@
+// Copyright (c) 2026 The Gocov Authors.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// configFileName is the name of the optional configuration file,
+// read from the working directory.
+const configFileName = ".gocov.toml"
+
+// configValue is a value from the configuration file, along with
+// the line on which it was defined.
+type configValue struct {
+	line   int
+	values []string
+}
+
+// config holds the contents of a configuration file, keyed by
+// section (command name) and then by key (flag name).
+type config map[string]map[string]configValue
+
+// applyConfig sets the flags in fs from the configuration file and
+// then from the environment. The section of the configuration file,
+// and the prefix of the environment variables, are determined by
+// the name of the flag set; e.g. the "quiet" flag of the "test"
+// command may be set by:
+//
+//	[test]
+//	quiet = true
+//
+// or by GOCOV_TEST_QUIET=true. Flags on the command line should be
+// parsed after calling applyConfig, so that they take precedence.
+// Values of repeatable flags from each source replace, rather than
+// add to, those from the sources before it.
+func applyConfig(fs *flag.FlagSet) error {
+	data, err := ioutil.ReadFile(configFileName)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err == nil {
+		cfg, err := parseConfig(data)
+		if err != nil {
+			return fmt.Errorf("%s: %v", configFileName, err)
+		}
+		if err := cfg.apply(fs); err != nil {
+			return fmt.Errorf("%s: %v", configFileName, err)
+		}
+		endSource(fs)
+	}
+	if err := applyEnv(fs, os.Environ()); err != nil {
+		return err
+	}
+	endSource(fs)
+	return nil
+}
+
+// endSource marks the values of the repeatable flags in fs as coming
+// from an earlier source, so that the next values set replace them.
+func endSource(fs *flag.FlagSet) {
+	fs.VisitAll(func(f *flag.Flag) {
+		if r, ok := f.Value.(interface{ endSource() }); ok {
+			r.endSource()
+		}
+	})
+}
+
+// repeatedFlag holds the values of a flag that may be repeated. It is
+// embedded in flag.Value implementations, whose Set method calls add.
+type repeatedFlag struct {
+	values []string
+
+	// earlier is true if the values came from an earlier source
+	// than the one now being applied, and are to be replaced.
+	earlier bool
+}
+
+func (f *repeatedFlag) String() string {
+	return strings.Join(f.values, " ")
+}
+
+func (f *repeatedFlag) add(value string) {
+	if f.earlier {
+		f.values, f.earlier = nil, false
+	}
+	f.values = append(f.values, value)
+}
+
+func (f *repeatedFlag) endSource() {
+	f.earlier = true
+}
+
+// apply sets the flags in fs from the section of the configuration
+// with the same name. Unknown keys are reported, and otherwise ignored.
+func (cfg config) apply(fs *flag.FlagSet) error {
+	for key, value := range cfg[fs.Name()] {
+		if fs.Lookup(key) == nil {
+			fmt.Fprintf(os.Stderr, "warning: %s:%d: unknown key %q in [%s]\n",
+				configFileName, value.line, key, fs.Name())
+			continue
+		}
+		for _, v := range value.values {
+			if err := fs.Set(key, v); err != nil {
+				return fmt.Errorf("%d: invalid value %q for %q: %v", value.line, v, key, err)
+			}
+		}
+	}
+	return nil
+}
+
+// applyEnv sets the flags in fs from environment variables of the
+// form GOCOV_<COMMAND>_<FLAG>, where '-' in flag names is replaced
+// with '_'.
+func applyEnv(fs *flag.FlagSet, environ []string) error {
+	var err error
+	fs.VisitAll(func(f *flag.Flag) {
+		name := strings.ToUpper("GOCOV_" + fs.Name() + "_" + f.Name)
+		name = strings.Replace(name, "-", "_", -1)
+		for _, kv := range environ {
+			if !strings.HasPrefix(kv, name+"=") || err != nil {
+				continue
+			}
+			value := kv[len(name)+1:]
+			if setErr := fs.Set(f.Name, value); setErr != nil {
+				err = fmt.Errorf("invalid value %q for %s: %v", value, name, setErr)
+			}
+		}
+	})
+	return err
+}
+
+// parseConfig parses the subset of TOML used by the configuration
+// file: sections, and keys with string, boolean, numeric or string
+// array values.
+func parseConfig(data []byte) (config, error) {
+	cfg := make(config)
+	section := ""
+	for i, line := range strings.Split(string(data), "\n") {
+		lineno := i + 1
+		line = strings.TrimSpace(stripComment(line))
+		if line == "" {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			section = strings.TrimSpace(line[1 : len(line)-1])
+			continue
+		}
+		equals := strings.Index(line, "=")
+		if equals < 0 {
+			return nil, fmt.Errorf("%d: expected key = value", lineno)
+		}
+		key := strings.TrimSpace(line[:equals])
+		values, err := parseConfigValue(strings.TrimSpace(line[equals+1:]))
+		if err != nil {
+			return nil, fmt.Errorf("%d: %v", lineno, err)
+		}
+		if section == "" {
+			return nil, fmt.Errorf("%d: key %q is not in a section", lineno, key)
+		}
+		if cfg[section] == nil {
+			cfg[section] = make(map[string]configValue)
+		}
+		cfg[section][key] = configValue{line: lineno, values: values}
+	}
+	return cfg, nil
+}
+
+// parseConfigValue parses a single value, returning each element
+// of an array as a separate value.
+func parseConfigValue(s string) ([]string, error) {
+	if strings.HasPrefix(s, "[") && strings.HasSuffix(s, "]") {
+		var values []string
+		for _, elem := range splitArray(s[1 : len(s)-1]) {
+			elem = strings.TrimSpace(elem)
+			if elem == "" {
+				continue
+			}
+			value, err := parseConfigScalar(elem)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, value)
+		}
+		return values, nil
+	}
+	value, err := parseConfigScalar(s)
+	if err != nil {
+		return nil, err
+	}
+	return []string{value}, nil
+}
+
+func parseConfigScalar(s string) (string, error) {
+	switch {
+	case strings.HasPrefix(s, `"`):
+		return strconv.Unquote(s)
+	case strings.HasPrefix(s, "'"):
+		if len(s) < 2 || !strings.HasSuffix(s, "'") {
+			return "", fmt.Errorf("unterminated string %s", s)
+		}
+		return s[1 : len(s)-1], nil
+	case s == "":
+		return "", fmt.Errorf("missing value")
+	}
+	return s, nil
+}
+
+// stripComment removes a trailing comment from a line, ignoring
+// any '#' characters within strings.
+func stripComment(line string) string {
+	var quote byte
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case quote == '"' && c == '\\':
+			i++ // skip the escaped character
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '#':
+			return line[:i]
+		}
+	}
+	return line
+}
+
+// splitArray splits the elements of an array on commas, ignoring
+// any commas within strings.
+func splitArray(s string) []string {
+	var elems []string
+	var quote byte
+	start := 0
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote == '"' && c == '\\':
+			i++ // skip the escaped character
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == ',':
+			elems = append(elems, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(elems, s[start:])
+}
@@ -0,0 +1,189 @@
+// Copyright (c) 2026 The Gocov Authors.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package main
+
+import (
+	"flag"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseConfig(t *testing.T) {
+	cfg, err := parseConfig([]byte(`
+# comment
+[report]
+rel = true # trailing comment
+format = "a # b"
+names = ["x", 'y,z']
+
+[test]
+timeout = 10s
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := map[string]map[string][]string{
+		"report": {"rel": {"true"}, "format": {"a # b"}, "names": {"x", "y,z"}},
+		"test":   {"timeout": {"10s"}},
+	}
+	for section, keys := range expect {
+		for key, values := range keys {
+			if got := cfg[section][key].values; !reflect.DeepEqual(got, values) {
+				t.Errorf("[%s] %s: got %q, expected %q", section, key, got, values)
+			}
+		}
+	}
+
+	for _, bad := range []string{"key = 1", "[report]\nkey", "[report]\nkey = 'x"} {
+		if _, err := parseConfig([]byte(bad)); err == nil {
+			t.Errorf("expected an error parsing %q", bad)
+		}
+	}
+}
+
+func TestConfigPrecedence(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gocov")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Unsetenv("GOCOV_EXAMPLE_ENV_VALUE")
+
+	newFlags := func() (*flag.FlagSet, map[string]*string) {
+		fs := flag.NewFlagSet("example", flag.ContinueOnError)
+		values := make(map[string]*string)
+		for _, name := range []string{"default", "file", "env-value", "cmdline"} {
+			values[name] = fs.String(name, "default", "")
+		}
+		return fs, values
+	}
+	config := strings.Join([]string{
+		"[example]",
+		`file = "file"`,
+		`env-value = "file"`,
+		`cmdline = "file"`,
+	}, "\n")
+	if err := ioutil.WriteFile(configFileName, []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+	os.Setenv("GOCOV_EXAMPLE_ENV_VALUE", "env")
+
+	fs, values := newFlags()
+	if err := applyConfig(fs); err != nil {
+		t.Fatal(err)
+	}
+	if err := fs.Parse([]string{"-cmdline", "cmdline"}); err != nil {
+		t.Fatal(err)
+	}
+	expect := map[string]string{
+		"default":   "default",
+		"file":      "file",
+		"env-value": "env",
+		"cmdline":   "cmdline",
+	}
+	for name, value := range expect {
+		if *values[name] != value {
+			t.Errorf("-%s: got %q, expected %q", name, *values[name], value)
+		}
+	}
+
+	// Unknown keys are ignored, and invalid values are reported.
+	if err := ioutil.WriteFile(configFileName, []byte("[example]\nunknown = 1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	fs, _ = newFlags()
+	if _, stderr := captureOutput(t, func() { err = applyConfig(fs) }); err != nil {
+		t.Error(err)
+	} else if !strings.Contains(stderr, `unknown key "unknown"`) {
+		t.Errorf("expected a warning, got %q", stderr)
+	}
+	if err := ioutil.WriteFile(configFileName, []byte("[example]\nflag = maybe\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	fs = flag.NewFlagSet("example", flag.ContinueOnError)
+	fs.Bool("flag", false, "")
+	if err := applyConfig(fs); err == nil {
+		t.Error("expected an error for an invalid value")
+	}
+}
+
+func TestConfigPrecedenceRepeated(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gocov")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Unsetenv("GOCOV_EXAMPLE_ENV")
+
+	config := strings.Join([]string{
+		"[example]",
+		`file = ["file1", "file2"]`,
+		`env = ["file1", "file2"]`,
+		`cmdline = ["file1", "file2"]`,
+	}, "\n")
+	if err := ioutil.WriteFile(configFileName, []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+	os.Setenv("GOCOV_EXAMPLE_ENV", "env")
+
+	fs := flag.NewFlagSet("example", flag.ContinueOnError)
+	values := make(map[string]*tagsFlag)
+	for _, name := range []string{"file", "env", "cmdline"} {
+		values[name] = &tagsFlag{}
+		fs.Var(values[name], name, "")
+	}
+	if err := applyConfig(fs); err != nil {
+		t.Fatal(err)
+	}
+	if err := fs.Parse([]string{"-cmdline", "cmdline1", "-cmdline", "cmdline2"}); err != nil {
+		t.Fatal(err)
+	}
+	// Each source replaces the values of the sources before it.
+	expect := map[string][]string{
+		"file":    {"file1", "file2"},
+		"env":     {"env"},
+		"cmdline": {"cmdline1", "cmdline2"},
+	}
+	for name, value := range expect {
+		if !reflect.DeepEqual(values[name].values, value) {
+			t.Errorf("-%s: got %q, expected %q", name, values[name].values, value)
+		}
+	}
+}
@@ -0,0 +1,156 @@
+// Copyright (c) 2026 The Gocov Authors.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/axw/gocov"
+)
+
+var (
+	contributionFlags   = flag.NewFlagSet("contribution", flag.ExitOnError)
+	contributionRunFlag = contributionFlags.String(
+		"run", "",
+		"Regular expression selecting the tests whose unique contribution to coverage is reported, as for go test -run")
+)
+
+// contributionStatement is a statement covered only by the tests
+// selected by -run.
+type contributionStatement struct {
+	pkg, function, file string
+	start, end          int
+}
+
+// testContribution reports the statements of the packages that are
+// covered by the tests matching -run and by no other test. The tests
+// of each package are run twice, once in full and once skipping the
+// selected tests, and the statements reached only by the full run are
+// reported.
+func testContribution(args []string) error {
+	contributionFlags.Parse(args)
+	if *contributionRunFlag == "" {
+		return errors.New("usage: gocov contribution -run <regexp> [packages]")
+	}
+	pkgs := contributionFlags.Args()
+	if len(pkgs) == 0 {
+		pkgs = []string{"."}
+	}
+	pkgs, err := resolvePackages(pkgs)
+	if err != nil {
+		return err
+	}
+	tmpDir, err := ioutil.TempDir("", "gocov")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	var full, skipped []string
+	for i, pkg := range pkgs {
+		fullFile := filepath.Join(tmpDir, fmt.Sprintf("test%d.cov", 2*i))
+		if err := goTest(context.Background(), pkg, fullFile, nil); err != nil {
+			return err
+		}
+		skippedFile := filepath.Join(tmpDir, fmt.Sprintf("test%d.cov", 2*i+1))
+		if err := goTest(context.Background(), pkg, skippedFile, []string{"-skip", *contributionRunFlag}); err != nil {
+			return err
+		}
+		full = append(full, fullFile)
+		skipped = append(skipped, skippedFile)
+	}
+	fullPackages, err := mergeProfiles(full...)
+	if err != nil {
+		return err
+	}
+	skippedPackages, err := mergeProfiles(skipped...)
+	if err != nil {
+		return err
+	}
+	unique, reached := uniqueCoverage(fullPackages, skippedPackages)
+	return printContribution(os.Stdout, unique, reached, *contributionRunFlag)
+}
+
+// uniqueCoverage returns the statements reached in full but not in
+// without, and the number of statements reached in full.
+func uniqueCoverage(full, without []*gocov.Package) (unique []contributionStatement, reached int) {
+	type statementKey struct {
+		file  string
+		start int
+	}
+	reachedWithout := make(map[statementKey]bool)
+	for _, pkg := range without {
+		for _, fn := range pkg.Functions {
+			for _, stmt := range fn.Statements {
+				if stmt.Reached > 0 {
+					reachedWithout[statementKey{fn.File, stmt.Start}] = true
+				}
+			}
+		}
+	}
+	for _, pkg := range full {
+		for _, fn := range pkg.Functions {
+			for _, stmt := range fn.Statements {
+				if stmt.Reached == 0 {
+					continue
+				}
+				reached++
+				if !reachedWithout[statementKey{fn.File, stmt.Start}] {
+					unique = append(unique, contributionStatement{pkg.Name, fn.Name, fn.File, stmt.Start, stmt.End})
+				}
+			}
+		}
+	}
+	sort.Slice(unique, func(i, j int) bool {
+		if unique[i].file != unique[j].file {
+			return unique[i].file < unique[j].file
+		}
+		return unique[i].start < unique[j].start
+	})
+	return unique, reached
+}
+
+// printContribution writes the position and function of each of the
+// statements covered only by the tests matching pattern, followed by
+// their number.
+func printContribution(w io.Writer, unique []contributionStatement, reached int, pattern string) error {
+	files := newSourceFiles()
+	for _, stmt := range unique {
+		pos := workingDirRelative(stmt.file)
+		if source, err := files.file(stmt.file); err == nil {
+			startLine, startCol := offsetPosition(source, stmt.start)
+			endLine, endCol := offsetPosition(source, stmt.end)
+			pos = fmt.Sprintf("%s:%d.%d,%d.%d", pos, startLine, startCol, endLine, endCol)
+		}
+		fmt.Fprintf(w, "%s\t%s.%s\n", pos, stmt.pkg, stmt.function)
+	}
+	_, err := fmt.Fprintf(w, "%d of %d statements reached are covered only by tests matching %q\n",
+		len(unique), reached, pattern)
+	return err
+}
@@ -0,0 +1,51 @@
+// Copyright (c) 2026 The Gocov Authors.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestContribution(t *testing.T) {
+	// TestNegative shares Classify's if statement with TestPositive,
+	// but alone reaches its first return and all of Negate.
+	defer resetFlags(contributionFlags)
+	var err error
+	stdout, stderr := captureOutput(t, func() {
+		err = testContribution([]string{"-run", "TestNegative", "./testdata/contribution"})
+	})
+	if err != nil {
+		t.Fatalf("%v\n%s", err, stderr)
+	}
+	const pkg = "github.com/axw/gocov/gocov/testdata/contribution"
+	expected := "testdata/contribution/contribution.go:7.3,7.20\t" + pkg + ".Classify\n" +
+		"testdata/contribution/contribution.go:14.2,14.11\t" + pkg + ".Negate\n" +
+		"2 of 4 statements reached are covered only by tests matching \"TestNegative\"\n"
+	if stdout != expected {
+		t.Errorf("got:\n%s\nexpected:\n%s", stdout, expected)
+	}
+
+	resetFlags(contributionFlags)
+	if err := testContribution([]string{"./testdata/contribution"}); err == nil || !strings.Contains(err.Error(), "usage") {
+		t.Errorf("expected a usage error without -run, got %v", err)
+	}
+}
@@ -26,7 +26,12 @@ import (
 	"go/build"
 	"go/parser"
 	"go/token"
+	"io/ioutil"
+	"os"
+	"path"
 	"path/filepath"
+	"strconv"
+	"strings"
 
 	"golang.org/x/tools/cover"
 
@@ -35,24 +40,9 @@ import (
 )
 
 func convertProfiles(filenames ...string) error {
-	var ps gocovutil.Packages
-	for i := range filenames {
-		converter := converter{
-			packages: make(map[string]*gocov.Package),
-		}
-		profiles, err := cover.ParseProfiles(filenames[i])
-		if err != nil {
-			return err
-		}
-		for _, p := range profiles {
-			if err := converter.convertProfile(p); err != nil {
-				return err
-			}
-		}
-
-		for _, pkg := range converter.packages {
-			ps.AddPackage(pkg)
-		}
+	ps, err := mergeProfiles(filenames...)
+	if err != nil {
+		return err
 	}
 	bytes, err := marshalJson(ps)
 	if err != nil {
@@ -62,8 +52,80 @@ func convertProfiles(filenames ...string) error {
 	return nil
 }
 
+// mergeProfiles converts the named coverage profiles, and merges
+// their coverage information.
+func mergeProfiles(filenames ...string) (gocovutil.Packages, error) {
+	// A single converter is used for all of the profiles, so that
+	// profiles of the same package built with different files, such
+	// as with different build tags, are combined: each source file's
+	// functions are added once, and its counts are summed.
+	converter := converter{
+		packages:   make(map[string]*gocov.Package),
+		statements: make(map[string][]statement),
+	}
+	var profiles []*cover.Profile
+	for i := range filenames {
+		p, err := cover.ParseProfiles(filenames[i])
+		if err != nil {
+			return nil, err
+		}
+		profiles = append(profiles, p...)
+	}
+	converter.dirs = listPackageDirs(profiles)
+	for _, p := range profiles {
+		if err := converter.convertProfile(p); err != nil {
+			return nil, err
+		}
+	}
+	var ps gocovutil.Packages
+	for _, pkg := range converter.packages {
+		ps.AddPackage(pkg)
+	}
+	return ps, nil
+}
+
 type converter struct {
 	packages map[string]*gocov.Package
+
+	// statements holds the statements of each source file that has
+	// been converted. A source file may be named by more than one
+	// profile if it contains //line directives.
+	statements map[string][]statement
+
+	// dirs holds the directory of each package named by the
+	// profiles, as listed by "go list".
+	dirs map[string]string
+}
+
+// listPackageDirs returns the directories of the packages whose files
+// are named by the profiles, listing them all with a single "go list"
+// so that they are found as the go command finds them, with modules
+// and vendoring. If the go command fails, such as when converting
+// profiles without it, the map is empty, and the packages are instead
+// found by build.Import when converted.
+func listPackageDirs(profiles []*cover.Profile) map[string]string {
+	dirs := make(map[string]string)
+	var pkgs []string
+	seen := make(map[string]bool)
+	for _, p := range profiles {
+		if pkg := path.Dir(p.FileName); !seen[pkg] {
+			seen[pkg] = true
+			pkgs = append(pkgs, pkg)
+		}
+	}
+	if len(pkgs) == 0 {
+		return dirs
+	}
+	output, err := goList("{{.ImportPath}}\t{{.Dir}}", pkgs, ioutil.Discard)
+	if err != nil {
+		return dirs
+	}
+	for _, line := range strings.Split(string(output), "\n") {
+		if fields := strings.Split(line, "\t"); len(fields) == 2 && fields[1] != "" {
+			dirs[fields[0]] = fields[1]
+		}
+	}
+	return dirs
 }
 
 // wrapper for gocov.Statement
@@ -73,7 +135,7 @@ type statement struct {
 }
 
 func (c *converter) convertProfile(p *cover.Profile) error {
-	file, pkgpath, err := findFile(p.FileName)
+	file, pkgpath, err := findFile(p.FileName, c.dirs)
 	if err != nil {
 		return err
 	}
@@ -82,13 +144,45 @@ func (c *converter) convertProfile(p *cover.Profile) error {
 		pkg = &gocov.Package{Name: pkgpath}
 		c.packages[pkgpath] = pkg
 	}
+	stmts, ok := c.statements[file]
+	if !ok {
+		stmts, err = c.convertFile(pkg, file)
+		if err != nil {
+			return err
+		}
+		c.statements[file] = stmts
+	}
+	// For each profile block in the file, find the statement(s) it
+	// covers and increment the Reached field(s).
+	blocks := p.Blocks
+	for _, s := range stmts {
+		for i, b := range blocks {
+			if b.StartLine > s.endLine || (b.StartLine == s.endLine && b.StartCol >= s.endCol) {
+				// Past the end of the statement
+				blocks = blocks[i:]
+				break
+			}
+			if b.EndLine < s.startLine || (b.EndLine == s.startLine && b.EndCol <= s.startCol) {
+				// Before the beginning of the statement
+				continue
+			}
+			s.Reached += int64(b.Count)
+			break
+		}
+	}
+	return nil
+}
+
+// convertFile adds the functions in the named source file to pkg,
+// and returns their statements.
+func (c *converter) convertFile(pkg *gocov.Package, file string) ([]statement, error) {
 	// Find function and statement extents; create corresponding
 	// gocov.Functions and gocov.Statements, and keep a separate
 	// slice of gocov.Statements so we can match them with profile
 	// blocks.
 	extents, err := findFuncs(file)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	var stmts []statement
 	for _, fe := range extents {
@@ -108,38 +202,74 @@ func (c *converter) convertProfile(p *cover.Profile) error {
 		}
 		pkg.Functions = append(pkg.Functions, f)
 	}
-	// For each profile block in the file, find the statement(s) it
-	// covers and increment the Reached field(s).
-	blocks := p.Blocks
-	for _, s := range stmts {
-		for i, b := range blocks {
-			if b.StartLine > s.endLine || (b.StartLine == s.endLine && b.StartCol >= s.endCol) {
-				// Past the end of the statement
-				blocks = blocks[i:]
-				break
-			}
-			if b.EndLine < s.startLine || (b.EndLine == s.startLine && b.EndCol <= s.startCol) {
-				// Before the beginning of the statement
-				continue
-			}
-			s.Reached += int64(b.Count)
-			break
-		}
-	}
-	return nil
+	return stmts, nil
 }
 
-// findFile finds the location of the named file in GOROOT, GOPATH etc.
-func findFile(file string) (filename string, pkgpath string, err error) {
+// findFile finds the location of the named file, in the directory
+// listed for its package in dirs, or otherwise in GOROOT, GOPATH etc.
+func findFile(file string, dirs map[string]string) (filename string, pkgpath string, err error) {
 	dir, file := filepath.Split(file)
 	if dir != "" {
 		dir = dir[:len(dir)-1] // drop trailing '/'
 	}
-	pkg, err := build.Import(dir, ".", build.FindOnly)
+	pkg := &build.Package{ImportPath: dir, Dir: dirs[dir]}
+	if pkg.Dir == "" {
+		pkg, err = build.Import(dir, ".", build.FindOnly)
+		if err != nil {
+			return "", "", fmt.Errorf("can't find %q: %v", file, err)
+		}
+	}
+	filename = filepath.Join(pkg.Dir, file)
+	if _, err := os.Stat(filename); os.IsNotExist(err) {
+		// Profiles name the target of any //line directive in
+		// place of the source file containing it.
+		if source := findLineDirectiveSource(pkg.Dir, file); source != "" {
+			filename = source
+		}
+	}
+	return filename, pkg.ImportPath, nil
+}
+
+// findLineDirectiveSource returns the path of the Go source file in
+// dir containing a //line directive that refers to the named file,
+// or "" if there is none.
+func findLineDirectiveSource(dir, file string) string {
+	sources, err := filepath.Glob(filepath.Join(dir, "*.go"))
 	if err != nil {
-		return "", "", fmt.Errorf("can't find %q: %v", file, err)
+		return ""
 	}
-	return filepath.Join(pkg.Dir, file), pkg.ImportPath, nil
+	for _, source := range sources {
+		data, err := ioutil.ReadFile(source)
+		if err != nil {
+			continue
+		}
+		for _, line := range strings.Split(string(data), "\n") {
+			var target string
+			switch {
+			case strings.HasPrefix(line, "//line "):
+				target = line[len("//line "):]
+			case strings.HasPrefix(line, "/*line "):
+				target = line[len("/*line "):]
+				if end := strings.Index(target, "*/"); end >= 0 {
+					target = target[:end]
+				}
+			default:
+				continue
+			}
+			// Strip the line and optional column numbers.
+			for i := 0; i < 2; i++ {
+				if colon := strings.LastIndex(target, ":"); colon >= 0 {
+					if _, err := strconv.Atoi(target[colon+1:]); err == nil {
+						target = target[:colon]
+					}
+				}
+			}
+			if filepath.Base(target) == file {
+				return source
+			}
+		}
+	}
+	return ""
 }
 
 // findFuncs parses the file and returns a slice of FuncExtent descriptors.
@@ -154,6 +284,13 @@ func findFuncs(name string) ([]*FuncExtent, error) {
 	return visitor.funcs, nil
 }
 
+// position returns the position of pos in the file, ignoring //line
+// directives, as is done by "go tool cover" when recording positions
+// in coverage profiles.
+func position(fset *token.FileSet, pos token.Pos) token.Position {
+	return fset.PositionFor(pos, false)
+}
+
 type extent struct {
 	startOffset int
 	startLine   int
@@ -191,19 +328,15 @@ func (v *FuncVisitor) Visit(node ast.Node) ast.Visitor {
 		name = n.Name.Name
 		// Function name is prepended with "T." if there is a receiver, where
 		// T is the type of the receiver, dereferenced if it is a pointer.
-		if n.Recv != nil {
-			field := n.Recv.List[0]
-			switch recv := field.Type.(type) {
-			case *ast.StarExpr:
-				name = recv.X.(*ast.Ident).Name + "." + name
-			case *ast.Ident:
-				name = recv.Name + "." + name
+		if n.Recv != nil && len(n.Recv.List) > 0 {
+			if recv := receiverTypeName(n.Recv.List[0].Type); recv != "" {
+				name = recv + "." + name
 			}
 		}
 	}
 	if body != nil {
-		start := v.fset.Position(node.Pos())
-		end := v.fset.Position(node.End())
+		start := position(v.fset, node.Pos())
+		end := position(v.fset, node.End())
 		if name == "" {
 			name = fmt.Sprintf("@%d:%d", start.Line, start.Column)
 		}
@@ -225,6 +358,26 @@ func (v *FuncVisitor) Visit(node ast.Node) ast.Visitor {
 	return v
 }
 
+// receiverTypeName returns the name of a method's receiver type,
+// without any pointer indirection or type parameters.
+func receiverTypeName(expr ast.Expr) string {
+	switch t := expr.(type) {
+	case *ast.Ident:
+		return t.Name
+	case *ast.StarExpr:
+		return receiverTypeName(t.X)
+	case *ast.ParenExpr:
+		return receiverTypeName(t.X)
+	case *ast.IndexExpr:
+		// Generic type with a single type parameter, e.g. T[K].
+		return receiverTypeName(t.X)
+	case *ast.IndexListExpr:
+		// Generic type with multiple type parameters, e.g. T[K, V].
+		return receiverTypeName(t.X)
+	}
+	return ""
+}
+
 type StmtVisitor struct {
 	fset     *token.FileSet
 	function *FuncExtent
@@ -297,7 +450,7 @@ func (v *StmtVisitor) VisitStmt(s ast.Stmt) {
 		case *ast.CaseClause, *ast.CommClause, *ast.BlockStmt:
 			break
 		default:
-			start, end := v.fset.Position(s.Pos()), v.fset.Position(s.End())
+			start, end := position(v.fset, s.Pos()), position(v.fset, s.End())
 			se := &StmtExtent{
 				startOffset: start.Offset,
 				startLine:   start.Line,
@@ -0,0 +1,99 @@
+// Copyright (c) 2026 The Gocov Authors.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"golang.org/x/tools/cover"
+)
+
+// funcNames returns the names of the functions found in the named file,
+// and the number of statements in each.
+func funcNames(t *testing.T, filename string) map[string]int {
+	funcs, err := findFuncs(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := make(map[string]int)
+	for _, fe := range funcs {
+		names[fe.name] = len(fe.stmts)
+	}
+	return names
+}
+
+func TestFindFuncsGeneric(t *testing.T) {
+	names := funcNames(t, "testdata/convert/generic.go")
+	expect := map[string]int{
+		"List.Push": 1,
+		"Pair.Swap": 1,
+		"Map":       4,
+	}
+	if !reflect.DeepEqual(names, expect) {
+		t.Errorf("got %v, expected %v", names, expect)
+	}
+}
+
+func TestFindFuncsReceivers(t *testing.T) {
+	// Methods of the same name on value and pointer receivers of
+	// related types are reported separately.
+	names := funcNames(t, "testdata/convert/receivers.go")
+	expect := map[string]int{
+		"Value.Name":   1,
+		"Pointer.Name": 1,
+	}
+	if !reflect.DeepEqual(names, expect) {
+		t.Errorf("got %v, expected %v", names, expect)
+	}
+}
+
+func TestListPackageDirs(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	profiles := []*cover.Profile{
+		{FileName: "github.com/axw/gocov/gocov/testdata/simple/simple.go"},
+		{FileName: "github.com/axw/gocov/gocov/testdata/simple/other.go"},
+		{FileName: "github.com/axw/gocov/gocov/testdata/blank/blank.go"},
+		{FileName: "example.com/missing/missing.go"},
+	}
+	dirs := listPackageDirs(profiles)
+	expect := map[string]string{
+		"github.com/axw/gocov/gocov/testdata/simple": filepath.Join(wd, "testdata", "simple"),
+		"github.com/axw/gocov/gocov/testdata/blank":  filepath.Join(wd, "testdata", "blank"),
+	}
+	if !reflect.DeepEqual(dirs, expect) {
+		t.Errorf("got %q, expected %q", dirs, expect)
+	}
+
+	// Packages that go list could not find are found by build.Import.
+	filename, pkgpath, err := findFile("github.com/axw/gocov/gocov/testdata/blank/blank.go", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if filename != filepath.Join(wd, "testdata", "blank", "blank.go") || pkgpath != "github.com/axw/gocov/gocov/testdata/blank" {
+		t.Errorf("got %s in %s", filename, pkgpath)
+	}
+}
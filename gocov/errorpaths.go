@@ -0,0 +1,116 @@
+// Copyright (c) 2026 The Gocov Authors.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package main
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"strings"
+
+	"github.com/axw/gocov"
+)
+
+// errorPaths finds the blocks of "if err != nil" checks in source
+// files, to report the coverage of error handling for -error-paths.
+type errorPaths map[string]fileRanges
+
+// fileRanges holds the ranges found in a file, or the error reading
+// or parsing it, so that each file is parsed only once.
+type fileRanges struct {
+	ranges []offsetRange
+	err    error
+}
+
+// offsetRange is a range of offsets in a file, from start up to but
+// not including end.
+type offsetRange struct {
+	start, end int
+}
+
+// contains reports whether stmt, of fn, is within the block of an
+// error check.
+func (e errorPaths) contains(fn *gocov.Function, stmt *gocov.Statement) (bool, error) {
+	f, ok := e[fn.File]
+	if !ok {
+		f.ranges, f.err = findErrorPaths(fn.File)
+		e[fn.File] = f
+	}
+	if f.err != nil {
+		return false, f.err
+	}
+	for _, block := range f.ranges {
+		if stmt.Start >= block.start && stmt.Start < block.end {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// isErrorName reports whether an identifier is named as an error
+// usually is: err, or ending with Err or err, such as readErr.
+func isErrorName(name string) bool {
+	return name == "err" || strings.HasSuffix(name, "Err") || strings.HasSuffix(name, "err")
+}
+
+// findErrorPaths returns the extents of the blocks of the if
+// statements in the named file whose condition compares an error with
+// nil, such as "if err != nil". Without type information, errors are
+// recognized by their names.
+func findErrorPaths(filename string) ([]offsetRange, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, filename, nil, 0)
+	if err != nil {
+		return nil, err
+	}
+	isNil := func(expr ast.Expr) bool {
+		ident, ok := expr.(*ast.Ident)
+		return ok && ident.Name == "nil"
+	}
+	isError := func(expr ast.Expr) bool {
+		switch x := expr.(type) {
+		case *ast.Ident:
+			return isErrorName(x.Name)
+		case *ast.SelectorExpr:
+			return isErrorName(x.Sel.Name)
+		}
+		return false
+	}
+	var blocks []offsetRange
+	ast.Inspect(file, func(node ast.Node) bool {
+		ifStmt, ok := node.(*ast.IfStmt)
+		if !ok {
+			return true
+		}
+		cond, ok := ifStmt.Cond.(*ast.BinaryExpr)
+		if !ok || cond.Op != token.NEQ {
+			return true
+		}
+		if (isError(cond.X) && isNil(cond.Y)) || (isNil(cond.X) && isError(cond.Y)) {
+			blocks = append(blocks, offsetRange{
+				start: position(fset, ifStmt.Body.Lbrace).Offset,
+				end:   position(fset, ifStmt.Body.Rbrace).Offset + 1,
+			})
+		}
+		return true
+	})
+	return blocks, nil
+}
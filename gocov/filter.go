@@ -0,0 +1,178 @@
+// Copyright (c) 2026 The Gocov Authors.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package main
+
+import (
+	"fmt"
+	"go/token"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/axw/gocov"
+)
+
+// filterStatements returns copies of the packages retaining only the
+// statements for which keep returns true, and the functions that
+// have at least one statement retained.
+func filterStatements(packages []*gocov.Package, keep func(*gocov.Function, *gocov.Statement) (bool, error)) ([]*gocov.Package, error) {
+	var result []*gocov.Package
+	for _, pkg := range packages {
+		filtered := &gocov.Package{Name: pkg.Name}
+		for _, fn := range pkg.Functions {
+			var stmts []*gocov.Statement
+			for _, stmt := range fn.Statements {
+				ok, err := keep(fn, stmt)
+				if err != nil {
+					return nil, err
+				}
+				if ok {
+					stmts = append(stmts, stmt)
+				}
+			}
+			if len(stmts) > 0 {
+				copy := *fn
+				copy.Statements = stmts
+				filtered.Functions = append(filtered.Functions, &copy)
+			}
+		}
+		if len(filtered.Functions) > 0 {
+			result = append(result, filtered)
+		}
+	}
+	return result, nil
+}
+
+// lineRange is an inclusive range of lines in a file.
+type lineRange struct {
+	file       string
+	start, end int
+}
+
+// parseLineRanges parses ranges of lines, one per line, of the form
+// "file:start-end" or "file:line". Blank lines and lines beginning
+// with '#' are ignored.
+func parseLineRanges(data []byte) ([]lineRange, error) {
+	var ranges []lineRange
+	for i, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		colon := strings.LastIndex(line, ":")
+		if colon <= 0 {
+			return nil, fmt.Errorf("%d: expected file:start-end", i+1)
+		}
+		lr := lineRange{file: line[:colon]}
+		lines := strings.SplitN(line[colon+1:], "-", 2)
+		var err error
+		if lr.start, err = strconv.Atoi(lines[0]); err != nil {
+			return nil, fmt.Errorf("%d: invalid line number %q", i+1, lines[0])
+		}
+		lr.end = lr.start
+		if len(lines) == 2 {
+			if lr.end, err = strconv.Atoi(lines[1]); err != nil {
+				return nil, fmt.Errorf("%d: invalid line number %q", i+1, lines[1])
+			}
+		}
+		if lr.end < lr.start {
+			return nil, fmt.Errorf("%d: range ends before it starts", i+1)
+		}
+		ranges = append(ranges, lr)
+	}
+	return ranges, nil
+}
+
+// matchesFile reports whether the range applies to the named file.
+// Relative paths in ranges, such as those taken from a diff, match
+// any file with the same trailing path elements.
+func (lr lineRange) matchesFile(filename string) bool {
+	file := filepath.ToSlash(filepath.Clean(lr.file))
+	filename = filepath.ToSlash(filepath.Clean(filename))
+	if filepath.IsAbs(lr.file) {
+		return file == filename
+	}
+	return filename == file || strings.HasSuffix(filename, "/"+file)
+}
+
+// filterLines returns copies of the packages retaining only the
+// statements that overlap one of the ranges.
+func filterLines(packages []*gocov.Package, ranges []lineRange) ([]*gocov.Package, error) {
+	files := newSourceFiles()
+	return filterStatements(packages, func(fn *gocov.Function, stmt *gocov.Statement) (bool, error) {
+		for _, lr := range ranges {
+			if !lr.matchesFile(fn.File) {
+				continue
+			}
+			source, err := files.file(fn.File)
+			if err != nil {
+				return false, err
+			}
+			start, _ := offsetPosition(source, stmt.Start)
+			end, _ := offsetPosition(source, stmt.End)
+			if start <= lr.end && end >= lr.start {
+				return true, nil
+			}
+		}
+		return false, nil
+	})
+}
+
+// filterFile returns copies of the packages retaining only the
+// functions of the named file, matched as by the file of a line range.
+// It is an error for no function to match, as the file is then likely
+// misnamed or not among the files tested.
+func filterFile(packages []*gocov.Package, file string) ([]*gocov.Package, error) {
+	lr := lineRange{file: file}
+	var matched bool
+	filtered, _ := filterStatements(packages, func(fn *gocov.Function, stmt *gocov.Statement) (bool, error) {
+		match := lr.matchesFile(fn.File)
+		matched = matched || match
+		return match, nil
+	})
+	if !matched {
+		return nil, fmt.Errorf("no statements in %s; it must be a non-test file of the packages tested", file)
+	}
+	return filtered, nil
+}
+
+// isExportedFunction reports whether a function, named as by the
+// converter, is exported: a function or method with an exported name,
+// and in the case of a method, an exported receiver type. Function
+// literals, named by their position, are not exported.
+func isExportedFunction(name string) bool {
+	for _, part := range strings.Split(name, ".") {
+		if !token.IsExported(part) {
+			return false
+		}
+	}
+	return true
+}
+
+// filterExported returns copies of the packages retaining only their
+// exported functions.
+func filterExported(packages []*gocov.Package) []*gocov.Package {
+	// The filter never fails.
+	filtered, _ := filterStatements(packages, func(fn *gocov.Function, stmt *gocov.Statement) (bool, error) {
+		return isExportedFunction(fn.Name), nil
+	})
+	return filtered
+}
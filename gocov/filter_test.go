@@ -0,0 +1,98 @@
+// Copyright (c) 2026 The Gocov Authors.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/axw/gocov"
+)
+
+func TestParseLineRanges(t *testing.T) {
+	ranges, err := parseLineRanges([]byte("# changed lines\na/b.go:3-5\n\nc:\\d.go:7\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := []lineRange{{"a/b.go", 3, 5}, {`c:\d.go`, 7, 7}}
+	if !reflect.DeepEqual(ranges, expect) {
+		t.Errorf("got %v, expected %v", ranges, expect)
+	}
+	for _, bad := range []string{"a.go", "a.go:x", "a.go:5-3", "a.go:1-y"} {
+		if _, err := parseLineRanges([]byte(bad)); err == nil {
+			t.Errorf("expected an error parsing %q", bad)
+		}
+	}
+}
+
+func TestFilterLines(t *testing.T) {
+	packages := testPackages(t, "./testdata/simple")
+	// The range covers the final return statement of Covered, but
+	// not the if statement before it, nor the function Uncovered.
+	ranges := []lineRange{{"testdata/simple/simple.go", 7, 8}}
+	filtered, err := filterLines(packages, ranges)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(filtered) != 1 || len(filtered[0].Functions) != 1 {
+		t.Fatalf("expected a single function, got %+v", filtered)
+	}
+	fn := filtered[0].Functions[0]
+	if fn.Name != "Covered" || len(fn.Statements) != 1 {
+		t.Errorf("expected 1 statement of Covered, got %d of %s", len(fn.Statements), fn.Name)
+	}
+	if reached := statementsReached(filtered); reached["Covered"] != 0 {
+		t.Errorf("unexpected coverage: %v", reached)
+	}
+	// The original packages are not modified.
+	if reached := statementsReached(packages); !reflect.DeepEqual(reached, map[string]int{"Covered": 2, "Uncovered": 0}) {
+		t.Errorf("original packages modified: %v", reached)
+	}
+
+	if filtered, _ := filterLines(packages, []lineRange{{"other/simple.go", 1, 100}}); len(filtered) != 0 {
+		t.Errorf("expected no packages for a different file, got %+v", filtered)
+	}
+}
+
+func TestFilterExported(t *testing.T) {
+	r := newTestReport(&gocov.Package{
+		Name: "example.com/a",
+		Functions: []*gocov.Function{
+			newFunction("Exported", "a.go", 1, 0),
+			newFunction("unexported", "a.go", 1, 1, 1),
+			newFunction("T.Method", "a.go", 1, 1),
+			newFunction("T.method", "a.go", 0),
+			newFunction("t.Method", "a.go", 0),
+			newFunction("@12:3", "a.go", 0),
+		},
+	})
+	r.packages = filterExported(r.packages)
+	var names []string
+	for _, fn := range r.packages[0].Functions {
+		names = append(names, fn.Name)
+	}
+	if expect := []string{"Exported", "T.Method"}; !reflect.DeepEqual(names, expect) {
+		t.Errorf("got %q, expected %q", names, expect)
+	}
+	if statements, reached := r.totals(); statements != 4 || reached != 3 {
+		t.Errorf("got %d/%d statements reached, expected 3/4", reached, statements)
+	}
+}
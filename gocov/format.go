@@ -0,0 +1,728 @@
+// Copyright (c) 2026 The Gocov Authors.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"go/token"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"text/template"
+
+	"github.com/axw/gocov"
+)
+
+// reportFormatter writes a report in a particular format.
+type reportFormatter struct {
+	// description is a short description of the format.
+	description string
+
+	// write writes the report to w.
+	write func(w io.Writer, r *report) error
+}
+
+// reportFormats holds the formats accepted by "gocov report -format".
+var reportFormats = map[string]reportFormatter{
+	"text": {"tabular text report (the default)", writeTextReport},
+	"json": {"JSON coverage summary", writeJSONReport},
+	"uncovered": {
+		"JSON lines describing each statement that was not reached",
+		writeUncoveredReport,
+	},
+	"github-actions": {
+		"GitHub Actions workflow commands annotating each statement that was not reached",
+		writeGitHubActionsReport,
+	},
+	"treemap": {
+		"nested JSON of packages, files and functions for rendering as a treemap",
+		writeTreemapReport,
+	},
+	"sonarqube": {
+		"SonarQube generic test coverage XML",
+		writeSonarQubeReport,
+	},
+	"shields": {
+		"shields.io endpoint JSON for a coverage badge",
+		writeShieldsReport,
+	},
+	"csv": {
+		"CSV rows of the coverage of each function, for spreadsheets",
+		writeCSVReport,
+	},
+	"template": {
+		"the text/template named by -template, executed with the JSON summary",
+		writeTemplateReport,
+	},
+}
+
+// reportFormatNames returns the names of the formats in reportFormats,
+// ordered by name.
+func reportFormatNames() []string {
+	var names []string
+	for name := range reportFormats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// printReportFormats writes the name and description of each format,
+// for -list-formats.
+func printReportFormats(w io.Writer) {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	for _, name := range reportFormatNames() {
+		fmt.Fprintf(tw, "%s\t%s\n", name, reportFormats[name].description)
+	}
+	tw.Flush()
+}
+
+func writeTextReport(w io.Writer, r *report) error {
+	fmt.Fprintln(w)
+	printReport(w, r)
+	if r.missedSource > 0 {
+		return printMissedSource(w, r, r.missedSource)
+	}
+	return nil
+}
+
+// jsonReport is the structure output by the "json" format. Package
+// names are always the full import path.
+type jsonReport struct {
+	Packages   []jsonPackage
+	Statements int
+	Reached    int
+	Coverage   float64
+
+	// PackagesCovered is the number of packages with at least
+	// one statement reached.
+	PackagesCovered int
+
+	// ErrorPaths holds the coverage of the statements within
+	// error checks, with -error-paths.
+	ErrorPaths *jsonCoverage `json:",omitempty"`
+
+	// RecoveryPaths holds the coverage of the statements that handle
+	// recovered panics, with -recovery-paths.
+	RecoveryPaths *jsonCoverage `json:",omitempty"`
+
+	// Owners holds the coverage of each owner, with -by owner.
+	Owners []jsonCoverage `json:",omitempty"`
+
+	// Groups holds the coverage of each group of packages, with
+	// -group-depth.
+	Groups []jsonCoverage `json:",omitempty"`
+
+	// Implementations holds the coverage of each type implementing
+	// the interface, with -by interface.
+	Implementations []jsonCoverage `json:",omitempty"`
+
+	// Histogram holds the number of statements reached within
+	// ranges of times, if requested with -histogram.
+	Histogram []hitBucket `json:",omitempty"`
+}
+
+type jsonPackage struct {
+	Name       string
+	Statements int
+	Reached    int
+	Coverage   float64
+	Functions  []jsonCoverage `json:",omitempty"`
+	Files      []jsonCoverage `json:",omitempty"`
+}
+
+// jsonCoverage records the coverage of a function or file.
+type jsonCoverage struct {
+	Name       string
+	File       string `json:",omitempty"`
+	Statements int
+	Reached    int
+	Coverage   float64
+}
+
+// newJSONReport returns the summary of r output by the "json" format.
+func newJSONReport(r *report) jsonReport {
+	result := jsonReport{Packages: []jsonPackage{}}
+	for _, pkg := range r.packages {
+		jp := jsonPackage{Name: pkg.Name}
+		if r.by == "file" {
+			for _, file := range fileReports(pkg) {
+				jp.Files = append(jp.Files, jsonCoverage{
+					Name:       file.name,
+					Statements: file.statements,
+					Reached:    file.statementsReached,
+					Coverage:   percent(file.statementsReached, file.statements),
+				})
+			}
+		}
+		for _, fn := range functionReports(pkg) {
+			jp.Statements += len(fn.Statements)
+			jp.Reached += fn.statementsReached
+			if r.by != "file" {
+				jp.Functions = append(jp.Functions, jsonCoverage{
+					Name:       fn.Name,
+					File:       fn.File,
+					Statements: len(fn.Statements),
+					Reached:    fn.statementsReached,
+					Coverage:   percent(fn.statementsReached, len(fn.Statements)),
+				})
+			}
+		}
+		jp.Coverage = percent(jp.Reached, jp.Statements)
+		result.Statements += jp.Statements
+		result.Reached += jp.Reached
+		result.Packages = append(result.Packages, jp)
+	}
+	result.Coverage = percent(result.Reached, result.Statements)
+	result.PackagesCovered, _ = r.packagePresence()
+	if r.errorPaths != nil {
+		statements, reached := r.errorPathTotals()
+		result.ErrorPaths = &jsonCoverage{
+			Name:       "error paths",
+			Statements: statements,
+			Reached:    reached,
+			Coverage:   percent(reached, statements),
+		}
+	}
+	if r.recoveryPaths != nil {
+		statements, reached := r.recoveryPathTotals()
+		result.RecoveryPaths = &jsonCoverage{
+			Name:       "recovery paths",
+			Statements: statements,
+			Reached:    reached,
+			Coverage:   percent(reached, statements),
+		}
+	}
+	if r.by == "owner" {
+		for _, owner := range r.ownerReports() {
+			result.Owners = append(result.Owners, jsonCoverage{
+				Name:       owner.name,
+				Statements: owner.statements,
+				Reached:    owner.statementsReached,
+				Coverage:   percent(owner.statementsReached, owner.statements),
+			})
+		}
+	}
+	if r.groupDepth > 0 {
+		for _, group := range r.groupReports() {
+			result.Groups = append(result.Groups, jsonCoverage{
+				Name:       group.name,
+				Statements: group.statements,
+				Reached:    group.statementsReached,
+				Coverage:   percent(group.statementsReached, group.statements),
+			})
+		}
+	}
+	if r.by == "interface" {
+		for _, impl := range r.implementationReports() {
+			result.Implementations = append(result.Implementations, jsonCoverage{
+				Name:       impl.name,
+				Statements: impl.statements,
+				Reached:    impl.statementsReached,
+				Coverage:   percent(impl.statementsReached, impl.statements),
+			})
+		}
+	}
+	if r.histogram {
+		result.Histogram = r.hitHistogram()
+	}
+	return result
+}
+
+func writeJSONReport(w io.Writer, r *report) error {
+	data, err := json.MarshalIndent(newJSONReport(r), "", "\t")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(w, string(data))
+	return err
+}
+
+// uncoveredRange describes the extent of a statement that was not
+// reached, as output by the "uncovered" format.
+type uncoveredRange struct {
+	File      string `json:"file"`
+	StartLine int    `json:"startLine"`
+	StartCol  int    `json:"startCol"`
+	EndLine   int    `json:"endLine"`
+	EndCol    int    `json:"endCol"`
+}
+
+// uncoveredRanges returns the extents of all statements in the report
+// that were not reached, ordered by file and then by position.
+func uncoveredRanges(r *report) ([]uncoveredRange, error) {
+	var ranges []uncoveredRange
+	files := newSourceFiles()
+	for _, pkg := range r.packages {
+		for _, fn := range pkg.Functions {
+			var source *token.File
+			for _, stmt := range fn.Statements {
+				if stmt.Reached > 0 {
+					continue
+				}
+				if source == nil {
+					var err error
+					if source, err = files.file(fn.File); err != nil {
+						return nil, err
+					}
+				}
+				u := uncoveredRange{File: fn.File}
+				u.StartLine, u.StartCol = offsetPosition(source, stmt.Start)
+				u.EndLine, u.EndCol = offsetPosition(source, stmt.End)
+				ranges = append(ranges, u)
+			}
+		}
+	}
+	sort.Slice(ranges, func(i, j int) bool {
+		a, b := ranges[i], ranges[j]
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		if a.StartLine != b.StartLine {
+			return a.StartLine < b.StartLine
+		}
+		return a.StartCol < b.StartCol
+	})
+	return ranges, nil
+}
+
+func writeUncoveredReport(w io.Writer, r *report) error {
+	ranges, err := uncoveredRanges(r)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(w)
+	for _, u := range ranges {
+		if err := enc.Encode(u); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// printMissedSource prints the first line of each of the first n
+// statements that were not reached, by file and position, with
+// carets beneath the part of the line that the statement covers.
+func printMissedSource(w io.Writer, r *report, n int) error {
+	ranges, err := uncoveredRanges(r)
+	if err != nil {
+		return err
+	}
+	if len(ranges) > n {
+		ranges = ranges[:n]
+	}
+	sources := make(map[string][]string)
+	for _, u := range ranges {
+		lines, ok := sources[u.File]
+		if !ok {
+			data, err := ioutil.ReadFile(u.File)
+			if err != nil {
+				return err
+			}
+			lines = strings.Split(string(data), "\n")
+			sources[u.File] = lines
+		}
+		if u.StartLine > len(lines) {
+			continue
+		}
+		line := lines[u.StartLine-1]
+		end := len(line) + 1
+		if u.EndLine == u.StartLine && u.EndCol < end {
+			end = u.EndCol
+		}
+		fmt.Fprintf(w, "\n%s:%d:%d\n%s\n%s\n", u.File, u.StartLine, u.StartCol, line, caretLine(line, u.StartCol, end))
+	}
+	return nil
+}
+
+// caretLine returns a line of carets from column start up to, but not
+// including, column end of line, indented with the tabs of line so
+// that it lines up beneath it.
+func caretLine(line string, start, end int) string {
+	var indent []byte
+	for i := 0; i < start-1 && i < len(line); i++ {
+		if line[i] == '\t' {
+			indent = append(indent, '\t')
+		} else {
+			indent = append(indent, ' ')
+		}
+	}
+	width := end - start
+	if width < 1 {
+		width = 1
+	}
+	return string(indent) + strings.Repeat("^", width)
+}
+
+// coveredLines returns whether each line of each file with statements
+// is covered: a line is covered if every statement attributed to it
+// was reached. With -line-attribution "first", a statement is only
+// attributed to its first line. With "all", it is attributed to every
+// line it spans, except that a statement containing others, such as an
+// if statement, spans only the lines before the first of them.
+func (r *report) coveredLines() (map[string]map[int]bool, error) {
+	statements := make(map[string][]*gocov.Statement)
+	for _, pkg := range r.packages {
+		for _, fn := range pkg.Functions {
+			statements[fn.File] = append(statements[fn.File], fn.Statements...)
+		}
+	}
+	sources := newSourceFiles()
+	lines := make(map[string]map[int]bool)
+	for filename, stmts := range statements {
+		if len(stmts) == 0 {
+			continue
+		}
+		source, err := sources.file(filename)
+		if err != nil {
+			return nil, err
+		}
+		sort.SliceStable(stmts, func(i, j int) bool {
+			if stmts[i].Start != stmts[j].Start {
+				return stmts[i].Start < stmts[j].Start
+			}
+			return stmts[i].End > stmts[j].End
+		})
+		fileLines := make(map[int]bool)
+		lines[filename] = fileLines
+		for i, stmt := range stmts {
+			start, _ := offsetPosition(source, stmt.Start)
+			end := start
+			if r.lineAttribution != "first" {
+				end, _ = offsetPosition(source, stmt.End)
+				if i+1 < len(stmts) && stmts[i+1].Start < stmt.End {
+					if nested, _ := offsetPosition(source, stmts[i+1].Start); nested-1 < end {
+						end = nested - 1
+					}
+				}
+				if end < start {
+					end = start
+				}
+			}
+			for line := start; line <= end; line++ {
+				covered, ok := fileLines[line]
+				fileLines[line] = (covered || !ok) && stmt.Reached > 0
+			}
+		}
+	}
+	return lines, nil
+}
+
+// workingDirRelative returns the slash-separated path of filename
+// relative to the working directory, if it is within it, and
+// otherwise filename unchanged. Tools reading reports of files in a
+// repository expect paths relative to it, where they are usually run.
+func workingDirRelative(filename string) string {
+	wd, err := os.Getwd()
+	if err != nil {
+		return filename
+	}
+	rel, err := filepath.Rel(wd, filename)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return filename
+	}
+	return filepath.ToSlash(rel)
+}
+
+// githubPropertyEscaper escapes the values of the properties of a
+// GitHub Actions workflow command.
+var githubPropertyEscaper = strings.NewReplacer(
+	"%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C")
+
+// writeGitHubActionsReport writes a ::warning workflow command for each
+// statement that was not reached, up to r.maxAnnotations of them, so
+// that they are shown as annotations of a pull request's diff. Files
+// within the working directory are named relative to it, as GitHub
+// expects paths relative to the repository.
+func writeGitHubActionsReport(w io.Writer, r *report) error {
+	ranges, err := uncoveredRanges(r)
+	if err != nil {
+		return err
+	}
+	for i, u := range ranges {
+		if r.maxAnnotations > 0 && i == r.maxAnnotations {
+			_, err := fmt.Fprintf(w, "::notice::%d more statements not covered by tests\n", len(ranges)-i)
+			return err
+		}
+		file := workingDirRelative(u.File)
+		_, err := fmt.Fprintf(w, "::warning file=%s,line=%d,col=%d,endLine=%d,endColumn=%d::Statement not covered by tests\n",
+			githubPropertyEscaper.Replace(file), u.StartLine, u.StartCol, u.EndLine, u.EndCol)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// templateFuncs holds the functions available to templates executed
+// by the "template" format, in addition to the text/template builtins.
+var templateFuncs = template.FuncMap{
+	// percent formats a coverage percentage as in the text report.
+	"percent": func(coverage float64) string {
+		return fmt.Sprintf("%.2f%%", coverage)
+	},
+	// sortByCoverage returns a copy of a slice of packages,
+	// functions or files, ordered from least to most covered.
+	"sortByCoverage": func(items interface{}) (interface{}, error) {
+		switch items := items.(type) {
+		case []jsonPackage:
+			sorted := append([]jsonPackage(nil), items...)
+			sort.SliceStable(sorted, func(i, j int) bool {
+				return sorted[i].Coverage < sorted[j].Coverage
+			})
+			return sorted, nil
+		case []jsonCoverage:
+			sorted := append([]jsonCoverage(nil), items...)
+			sort.SliceStable(sorted, func(i, j int) bool {
+				return sorted[i].Coverage < sorted[j].Coverage
+			})
+			return sorted, nil
+		}
+		return nil, fmt.Errorf("cannot sort %T by coverage", items)
+	},
+}
+
+func writeTemplateReport(w io.Writer, r *report) error {
+	if r.template == "" {
+		return errors.New("-format template requires -template")
+	}
+	data, err := ioutil.ReadFile(r.template)
+	if err != nil {
+		return err
+	}
+	tmpl, err := template.New(filepath.Base(r.template)).Funcs(templateFuncs).Parse(string(data))
+	if err != nil {
+		return err
+	}
+	return tmpl.Execute(w, newJSONReport(r))
+}
+
+// sonarCoverage is the structure output by the "sonarqube" format, as
+// described at https://docs.sonarsource.com/sonarqube/latest/analyzing-source-code/test-coverage/generic-test-data/.
+type sonarCoverage struct {
+	XMLName xml.Name    `xml:"coverage"`
+	Version int         `xml:"version,attr"`
+	Files   []sonarFile `xml:"file"`
+}
+
+type sonarFile struct {
+	Path  string      `xml:"path,attr"`
+	Lines []sonarLine `xml:"lineToCover"`
+}
+
+type sonarLine struct {
+	LineNumber int  `xml:"lineNumber,attr"`
+	Covered    bool `xml:"covered,attr"`
+}
+
+// writeSonarQubeReport writes the lines of each file to which
+// statements are attributed by -line-attribution, as covered if all of
+// the statements attributed to the line were reached. Files are named
+// relative to the working directory if within it.
+func writeSonarQubeReport(w io.Writer, r *report) error {
+	lines, err := r.coveredLines()
+	if err != nil {
+		return err
+	}
+	result := sonarCoverage{Version: 1}
+	for filename, fileLines := range lines {
+		file := sonarFile{Path: workingDirRelative(filename)}
+		for line, covered := range fileLines {
+			file.Lines = append(file.Lines, sonarLine{line, covered})
+		}
+		sort.Slice(file.Lines, func(i, j int) bool {
+			return file.Lines[i].LineNumber < file.Lines[j].LineNumber
+		})
+		result.Files = append(result.Files, file)
+	}
+	sort.Slice(result.Files, func(i, j int) bool {
+		return result.Files[i].Path < result.Files[j].Path
+	})
+	data, err := xml.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "%s%s\n", xml.Header, data)
+	return err
+}
+
+// csvHeader is the header row written by the "csv" format.
+var csvHeader = []string{"package", "function", "file", "startLine", "statements", "covered", "percent", "hits"}
+
+// writeCSVReport writes a row for each function: its package, name,
+// file and the line on which it starts, its number of statements and
+// of those reached, its coverage percentage, and the number of times
+// its first statement was reached, which is the number of calls. The
+// header row is left out if r.csvHeader is false. Files within the
+// working directory are named relative to it.
+func writeCSVReport(w io.Writer, r *report) error {
+	cw := csv.NewWriter(w)
+	if r.csvHeader {
+		cw.Write(csvHeader)
+	}
+	files := newSourceFiles()
+	for _, pkg := range r.packages {
+		for _, fn := range functionReports(pkg) {
+			source, err := files.file(fn.File)
+			if err != nil {
+				return err
+			}
+			line, _ := offsetPosition(source, fn.Start)
+			var first *gocov.Statement
+			for _, stmt := range fn.Statements {
+				if first == nil || stmt.Start < first.Start {
+					first = stmt
+				}
+			}
+			var hits int64
+			if first != nil {
+				hits = first.Reached
+			}
+			cw.Write([]string{
+				pkg.Name,
+				fn.Name,
+				workingDirRelative(fn.File),
+				strconv.Itoa(line),
+				strconv.Itoa(len(fn.Statements)),
+				strconv.Itoa(fn.statementsReached),
+				strconv.FormatFloat(percent(fn.statementsReached, len(fn.Statements)), 'f', 2, 64),
+				strconv.FormatInt(hits, 10),
+			})
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// treemapNode is a node of the hierarchy output by the "treemap"
+// format: the report, its packages, their files, and the functions
+// within them. Value is the number of statements of the node, as is
+// the convention for treemaps such as d3's, and Covered the number of
+// those that were reached.
+type treemapNode struct {
+	Name     string         `json:"name"`
+	Value    int            `json:"value"`
+	Covered  int            `json:"covered"`
+	Children []*treemapNode `json:"children,omitempty"`
+}
+
+// newTreemap returns the root of the hierarchy output by the
+// "treemap" format, which is named by the module path with -rel.
+// Packages are named by their full import paths, as in the JSON
+// summary.
+func newTreemap(r *report) *treemapNode {
+	root := &treemapNode{Name: r.modulePath, Children: []*treemapNode{}}
+	if root.Name == "" {
+		root.Name = "coverage"
+	}
+	for _, pkg := range r.packages {
+		pkgNode := &treemapNode{Name: pkg.Name}
+		files := make(map[string]*treemapNode)
+		for _, file := range fileReports(pkg) {
+			fileNode := &treemapNode{
+				Name:    filepath.Base(file.name),
+				Value:   file.statements,
+				Covered: file.statementsReached,
+			}
+			files[file.name] = fileNode
+			pkgNode.Children = append(pkgNode.Children, fileNode)
+			pkgNode.Value += file.statements
+			pkgNode.Covered += file.statementsReached
+		}
+		for _, fn := range functionReports(pkg) {
+			fileNode := files[fn.File]
+			fileNode.Children = append(fileNode.Children, &treemapNode{
+				Name:    fn.Name,
+				Value:   len(fn.Statements),
+				Covered: fn.statementsReached,
+			})
+		}
+		root.Children = append(root.Children, pkgNode)
+		root.Value += pkgNode.Value
+		root.Covered += pkgNode.Covered
+	}
+	return root
+}
+
+func writeTreemapReport(w io.Writer, r *report) error {
+	return json.NewEncoder(w).Encode(newTreemap(r))
+}
+
+// shieldsBadge is the structure output by the "shields" format, as
+// described at https://shields.io/badges/endpoint-badge.
+type shieldsBadge struct {
+	SchemaVersion int    `json:"schemaVersion"`
+	Label         string `json:"label"`
+	Message       string `json:"message"`
+	Color         string `json:"color"`
+}
+
+// parseBadgeColors parses the -badge-colors flag: the coverage
+// percentages at which badges turn yellow and green.
+func parseBadgeColors(s string) ([2]float64, error) {
+	var colors [2]float64
+	fields := strings.Split(s, ",")
+	if len(fields) != 2 {
+		return colors, fmt.Errorf("expected two percentages, such as 50,80")
+	}
+	for i, field := range fields {
+		value, err := strconv.ParseFloat(strings.TrimSpace(field), 64)
+		if err != nil {
+			return colors, err
+		}
+		colors[i] = value
+	}
+	if colors[0] > colors[1] {
+		return colors, fmt.Errorf("%v is greater than %v", colors[0], colors[1])
+	}
+	return colors, nil
+}
+
+// badgeColor returns the color of a badge for the coverage.
+func badgeColor(coverage float64, colors [2]float64) string {
+	switch {
+	case coverage < colors[0]:
+		return "red"
+	case coverage < colors[1]:
+		return "yellow"
+	}
+	return "green"
+}
+
+func writeShieldsReport(w io.Writer, r *report) error {
+	statements, reached := r.totals()
+	coverage := percent(reached, statements)
+	return json.NewEncoder(w).Encode(shieldsBadge{
+		SchemaVersion: 1,
+		Label:         "coverage",
+		Message:       fmt.Sprintf("%.1f%%", coverage),
+		Color:         badgeColor(coverage, r.badgeColors),
+	})
+}
@@ -0,0 +1,190 @@
+// Copyright (c) 2026 The Gocov Authors.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// implementation is a type implementing the interface given by
+// -interface, and the methods with which it does so.
+type implementation struct {
+	// name is the type's package path and name, such as
+	// "example.com/shapes.Square".
+	name string
+
+	// methods are the functions implementing the interface's methods,
+	// in the form reported by the converter.
+	methods []methodFunction
+}
+
+// methodFunction identifies a method by its package and its name as
+// reported by the converter, such as "Square.Area". A promoted method
+// is identified by the type declaring it.
+type methodFunction struct {
+	pkg, name string
+}
+
+// findImplementations returns the types declared in the packages that
+// implement the interface named by iface, such as "io.Reader", or are
+// pointers to types that do. The packages are type-checked from
+// source, and their dependencies loaded from the export data built by
+// "go list -export".
+func findImplementations(iface string, pkgs []string) ([]implementation, error) {
+	dot := strings.LastIndex(iface, ".")
+	if dot <= 0 {
+		return nil, fmt.Errorf("expected an interface of the form package.Name, got %q", iface)
+	}
+	ifacePath, ifaceName := iface[:dot], iface[dot+1:]
+	const format = "{{.ImportPath}}\t{{.Export}}\t{{.Dir}}\t{{join .GoFiles \",\"}}"
+	output, err := goList(format, append([]string{ifacePath}, pkgs...), ioutil.Discard, "-export", "-deps")
+	if err != nil {
+		return nil, err
+	}
+	type listedPackage struct {
+		export, dir string
+		files       []string
+	}
+	listed := make(map[string]listedPackage)
+	for _, line := range strings.Split(string(output), "\n") {
+		if fields := strings.Split(line, "\t"); len(fields) == 4 {
+			listed[fields[0]] = listedPackage{fields[1], fields[2], strings.Split(fields[3], ",")}
+		}
+	}
+
+	fset := token.NewFileSet()
+	imp := importer.ForCompiler(fset, "gc", func(path string) (io.ReadCloser, error) {
+		if pkg := listed[path]; pkg.export != "" {
+			return os.Open(pkg.export)
+		}
+		return nil, fmt.Errorf("no export data for %s", path)
+	})
+	check := func(path string) *types.Package {
+		pkg, ok := listed[path]
+		if !ok {
+			return nil
+		}
+		var files []*ast.File
+		for _, name := range pkg.files {
+			file, err := parser.ParseFile(fset, filepath.Join(pkg.dir, name), nil, 0)
+			if err != nil {
+				return nil
+			}
+			files = append(files, file)
+		}
+		// Errors such as those of cgo packages are ignored, so that
+		// whatever could be checked is still used.
+		conf := types.Config{Importer: imp, Error: func(error) {}}
+		checked, _ := conf.Check(path, fset, files, nil)
+		return checked
+	}
+
+	checked := make(map[string]*types.Package)
+	for _, path := range pkgs {
+		if pkg := check(path); pkg != nil {
+			checked[path] = pkg
+		}
+	}
+	// An interface declared in one of the packages is taken from its
+	// checked source, for its types to be identical to those of the
+	// package's methods.
+	ifacePkg := checked[ifacePath]
+	if ifacePkg == nil {
+		if ifacePkg, err = imp.Import(ifacePath); err != nil {
+			return nil, fmt.Errorf("cannot load %s: %v", ifacePath, err)
+		}
+	}
+	obj, ok := ifacePkg.Scope().Lookup(ifaceName).(*types.TypeName)
+	if !ok {
+		return nil, fmt.Errorf("%s is not declared", iface)
+	}
+	ifaceType, ok := obj.Type().Underlying().(*types.Interface)
+	if !ok {
+		return nil, fmt.Errorf("%s is not an interface", iface)
+	}
+
+	var implementations []implementation
+	for _, path := range pkgs {
+		pkg := checked[path]
+		if pkg == nil {
+			continue
+		}
+		for _, name := range pkg.Scope().Names() {
+			typeName, ok := pkg.Scope().Lookup(name).(*types.TypeName)
+			if !ok || typeName.IsAlias() || types.IsInterface(typeName.Type()) {
+				continue
+			}
+			methodSet := types.NewMethodSet(typeName.Type())
+			if !types.Implements(typeName.Type(), ifaceType) {
+				ptr := types.NewPointer(typeName.Type())
+				if !types.Implements(ptr, ifaceType) {
+					continue
+				}
+				methodSet = types.NewMethodSet(ptr)
+			}
+			impl := implementation{name: path + "." + name}
+			for i := 0; i < ifaceType.NumMethods(); i++ {
+				method := ifaceType.Method(i)
+				sel := methodSet.Lookup(method.Pkg(), method.Name())
+				if sel == nil {
+					continue
+				}
+				fn := sel.Obj().(*types.Func)
+				recv := fn.Type().(*types.Signature).Recv()
+				if recv == nil || fn.Pkg() == nil {
+					continue
+				}
+				if recvName := namedTypeName(recv.Type()); recvName != "" {
+					impl.methods = append(impl.methods, methodFunction{fn.Pkg().Path(), recvName + "." + fn.Name()})
+				}
+			}
+			implementations = append(implementations, impl)
+		}
+	}
+	sort.Slice(implementations, func(i, j int) bool {
+		return implementations[i].name < implementations[j].name
+	})
+	return implementations, nil
+}
+
+// namedTypeName returns the name of a method's receiver type, without
+// any pointer indirection or type arguments, as it is named by the
+// converter.
+func namedTypeName(t types.Type) string {
+	if ptr, ok := t.(*types.Pointer); ok {
+		t = ptr.Elem()
+	}
+	if named, ok := t.(*types.Named); ok {
+		return named.Obj().Name()
+	}
+	return ""
+}
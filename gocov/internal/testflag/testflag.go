@@ -32,17 +32,26 @@ import "strings"
 type testFlagSpec struct {
 	name   string
 	isBool bool
+
+	// isBuild is true for flags given to the go command when it
+	// builds a test binary, rather than to the binary.
+	isBuild bool
 }
 
 var testFlagDefn = []*testFlagSpec{
 	// test-specific
-	{name: "i", isBool: true},
+	{name: "i", isBool: true, isBuild: true},
 	{name: "bench"},
 	{name: "benchmem", isBool: true},
 	{name: "benchtime"},
-	{name: "covermode"},
+	{name: "count"},
+	{name: "covermode", isBuild: true},
 	{name: "cpu"},
 	{name: "cpuprofile"},
+	{name: "failfast", isBool: true},
+	{name: "fuzz"},
+	{name: "fuzzminimizetime"},
+	{name: "fuzztime"},
 	{name: "memprofile"},
 	{name: "memprofilerate"},
 	{name: "blockprofile"},
@@ -50,31 +59,40 @@ var testFlagDefn = []*testFlagSpec{
 	{name: "parallel"},
 	{name: "run"},
 	{name: "short", isBool: true},
+	{name: "shuffle"},
 	{name: "timeout"},
 	{name: "trace"},
 	{name: "v", isBool: true},
 
 	// common build flags
-	{name: "a", isBool: true},
-	{name: "race", isBool: true},
-	{name: "x", isBool: true},
-	{name: "asmflags"},
-	{name: "buildmode"},
-	{name: "compiler"},
-	{name: "gccgoflags"},
-	{name: "gcflags"},
-	{name: "ldflags"},
-	{name: "linkshared", isBool: true},
-	{name: "pkgdir"},
-	{name: "tags"},
-	{name: "toolexec"},
+	{name: "a", isBool: true, isBuild: true},
+	{name: "race", isBool: true, isBuild: true},
+	{name: "x", isBool: true, isBuild: true},
+	{name: "asmflags", isBuild: true},
+	{name: "buildmode", isBuild: true},
+	{name: "compiler", isBuild: true},
+	{name: "gccgoflags", isBuild: true},
+	{name: "gcflags", isBuild: true},
+	{name: "ldflags", isBuild: true},
+	{name: "linkshared", isBool: true, isBuild: true},
+	{name: "pkgdir", isBuild: true},
+	{name: "tags", isBuild: true},
+	{name: "toolexec", isBuild: true},
 }
 
 // Split processes the arguments , separating flags and package
-// names as done by "go test".
+// names as done by "go test". The arguments from "-args" on are
+// passed to the test unchanged.
 func Split(args []string) (packageNames, passToTest []string) {
 	inPkg := false
 	for i := 0; i < len(args); i++ {
+		if isArgs(args[i]) {
+			if packageNames == nil {
+				packageNames = []string{}
+			}
+			passToTest = append(passToTest, args[i:]...)
+			break
+		}
 		if !strings.HasPrefix(args[i], "-") {
 			if !inPkg && packageNames == nil {
 				// First package name we've seen.
@@ -111,6 +129,56 @@ func Split(args []string) (packageNames, passToTest []string) {
 	return packageNames, passToTest
 }
 
+// SplitBuild separates the flags returned by Split into those given to
+// "go test -c" when building a test binary, and those given to the
+// binary, which are named with the "test." prefix that it expects.
+// Unknown flags, and the arguments following "-args", are given to the
+// binary unchanged, as by "go test".
+func SplitBuild(passToTest []string) (build, test []string) {
+	for i := 0; i < len(passToTest); i++ {
+		if isArgs(passToTest[i]) {
+			test = append(test, passToTest[i+1:]...)
+			break
+		}
+		n := parseTestFlag(passToTest, i)
+		f := lookupTestFlag(passToTest[i])
+		if n == 0 || f == nil {
+			test = append(test, passToTest[i])
+			continue
+		}
+		if f.isBuild {
+			build = append(build, passToTest[i:i+n]...)
+		} else {
+			name := strings.TrimPrefix(strings.TrimLeft(passToTest[i], "-"), "test.")
+			test = append(test, "-test."+name)
+			test = append(test, passToTest[i+1:i+n]...)
+		}
+		i += n - 1
+	}
+	return build, test
+}
+
+// isArgs reports whether arg is the "-args" flag, after which the
+// arguments are for the test binary.
+func isArgs(arg string) bool {
+	return arg == "-args" || arg == "--args"
+}
+
+// lookupTestFlag returns the definition of the flag arg, or nil if it
+// is not a known flag.
+func lookupTestFlag(arg string) *testFlagSpec {
+	name := strings.TrimPrefix(strings.TrimLeft(arg, "-"), "test.")
+	if equals := strings.Index(name, "="); equals >= 0 {
+		name = name[:equals]
+	}
+	for _, f := range testFlagDefn {
+		if name == f.name {
+			return f
+		}
+	}
+	return nil
+}
+
 // parseTestFlag sees if argument i is a known flag and returns its
 // definition, value, and whether it consumed an extra word.
 func parseTestFlag(args []string, i int) (n int) {
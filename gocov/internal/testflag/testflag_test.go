@@ -47,6 +47,14 @@ var tests = []test{{
 	input:        []string{"--", "positional", "-v"},
 	packageNames: []string{},
 	passToTest:   []string{"--", "positional", "-v"},
+}, {
+	input:        []string{"./...", "-args", "-v", "positional", "-tags", "x"},
+	packageNames: []string{"./..."},
+	passToTest:   []string{"-args", "-v", "positional", "-tags", "x"},
+}, {
+	input:        []string{"-args", "positional"},
+	packageNames: []string{},
+	passToTest:   []string{"-args", "positional"},
 }, {
 	input:        []string{"-tags", "a b c", "./..."},
 	packageNames: []string{"./..."},
@@ -63,6 +71,14 @@ var tests = []test{{
 	input:        []string{"-h", "-?", "-help"},
 	packageNames: nil,
 	passToTest:   []string{"-h", "-?", "-help"},
+}, {
+	input:        []string{"-fuzz", "FuzzX", "-fuzztime", "10s", "./pkg"},
+	packageNames: []string{"./pkg"},
+	passToTest:   []string{"-fuzz", "FuzzX", "-fuzztime", "10s"},
+}, {
+	input:        []string{"-count=1", "-failfast", "./a", "./b", "-shuffle", "on"},
+	packageNames: []string{"./a", "./b"},
+	passToTest:   []string{"-count=1", "-failfast", "-shuffle", "on"},
 }, {
 	input:        []string{"--v", "--tags=a b c", "pkgname"},
 	packageNames: []string{"pkgname"},
@@ -81,3 +97,16 @@ func TestSplit(t *testing.T) {
 		}
 	}
 }
+
+func TestSplitBuild(t *testing.T) {
+	build, test := SplitBuild([]string{
+		"-race", "-run", "TestX", "--tags=a b", "-test.v", "-count=1", "-covermode", "atomic", "-custom", "value",
+		"-args", "-race", "-run", "x",
+	})
+	if expected := []string{"-race", "--tags=a b", "-covermode", "atomic"}; !reflect.DeepEqual(build, expected) {
+		t.Errorf("build mismatch: %q != %q", build, expected)
+	}
+	if expected := []string{"-test.run", "TestX", "-test.v", "-test.count=1", "-custom", "value", "-race", "-run", "x"}; !reflect.DeepEqual(test, expected) {
+		t.Errorf("test mismatch: %q != %q", test, expected)
+	}
+}
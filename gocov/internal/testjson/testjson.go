@@ -0,0 +1,96 @@
+// Copyright (c) 2026 The Gocov Authors.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+// Package testjson decodes the test events output by "go test -json",
+// as described by "go doc cmd/test2json".
+package testjson
+
+import (
+	"encoding/json"
+	"io"
+	"time"
+)
+
+// Event is a single test event.
+type Event struct {
+	Time    time.Time
+	Action  string
+	Package string
+	Test    string
+	Elapsed float64
+	Output  string
+
+	// FailedBuild is the import path of a package that failed to
+	// build, causing the package's tests to fail.
+	FailedBuild string
+}
+
+// Result is the outcome of a single test, or of a package if Test
+// is empty.
+type Result struct {
+	Package string
+	Test    string
+
+	// Action is the final action of the test: "pass", "fail"
+	// or "skip".
+	Action  string
+	Elapsed float64
+
+	// Output is the output of the test, excluding any output
+	// of its subtests.
+	Output string
+}
+
+// Decode reads test events from r until EOF, and returns the
+// outcome of each test and package in the order they completed.
+// If handle is non-nil, it is called with each event as it is
+// decoded.
+func Decode(r io.Reader, handle func(Event)) ([]Result, error) {
+	type key struct{ pkg, test string }
+	output := make(map[key]string)
+	var results []Result
+	dec := json.NewDecoder(r)
+	for {
+		var e Event
+		if err := dec.Decode(&e); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		if handle != nil {
+			handle(e)
+		}
+		k := key{e.Package, e.Test}
+		switch e.Action {
+		case "output":
+			output[k] += e.Output
+		case "pass", "fail", "skip":
+			results = append(results, Result{
+				Package: e.Package,
+				Test:    e.Test,
+				Action:  e.Action,
+				Elapsed: e.Elapsed,
+				Output:  output[k],
+			})
+			delete(output, k)
+		}
+	}
+	return results, nil
+}
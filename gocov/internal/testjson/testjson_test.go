@@ -0,0 +1,82 @@
+// Copyright (c) 2026 The Gocov Authors.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package testjson
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// events is a stream captured from "go test -json", abbreviated.
+const events = `{"Time":"2026-01-02T15:04:05Z","Action":"start","Package":"example.com/p"}
+{"Time":"2026-01-02T15:04:05Z","Action":"run","Package":"example.com/p","Test":"TestPass"}
+{"Time":"2026-01-02T15:04:05Z","Action":"output","Package":"example.com/p","Test":"TestPass","Output":"=== RUN   TestPass\n"}
+{"Time":"2026-01-02T15:04:05Z","Action":"output","Package":"example.com/p","Test":"TestPass","Output":"--- PASS: TestPass (0.00s)\n"}
+{"Time":"2026-01-02T15:04:05Z","Action":"pass","Package":"example.com/p","Test":"TestPass","Elapsed":0.01}
+{"Time":"2026-01-02T15:04:05Z","Action":"run","Package":"example.com/p","Test":"TestFail"}
+{"Time":"2026-01-02T15:04:05Z","Action":"output","Package":"example.com/p","Test":"TestFail","Output":"    p_test.go:9: failed\n"}
+{"Time":"2026-01-02T15:04:05Z","Action":"fail","Package":"example.com/p","Test":"TestFail","Elapsed":0.02}
+{"Time":"2026-01-02T15:04:05Z","Action":"run","Package":"example.com/p","Test":"TestSkip"}
+{"Time":"2026-01-02T15:04:05Z","Action":"skip","Package":"example.com/p","Test":"TestSkip"}
+{"Time":"2026-01-02T15:04:05Z","Action":"output","Package":"example.com/p","Output":"FAIL\n"}
+{"Time":"2026-01-02T15:04:05Z","Action":"fail","Package":"example.com/p","Elapsed":0.5}
+`
+
+func TestDecode(t *testing.T) {
+	var n int
+	results, err := Decode(strings.NewReader(events), func(Event) { n++ })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 12 {
+		t.Errorf("handled %d events, expected 12", n)
+	}
+	expect := []Result{{
+		Package: "example.com/p",
+		Test:    "TestPass",
+		Action:  "pass",
+		Elapsed: 0.01,
+		Output:  "=== RUN   TestPass\n--- PASS: TestPass (0.00s)\n",
+	}, {
+		Package: "example.com/p",
+		Test:    "TestFail",
+		Action:  "fail",
+		Elapsed: 0.02,
+		Output:  "    p_test.go:9: failed\n",
+	}, {
+		Package: "example.com/p",
+		Test:    "TestSkip",
+		Action:  "skip",
+	}, {
+		Package: "example.com/p",
+		Action:  "fail",
+		Elapsed: 0.5,
+		Output:  "FAIL\n",
+	}}
+	if !reflect.DeepEqual(results, expect) {
+		t.Errorf("got %+v\nexpected %+v", results, expect)
+	}
+
+	if _, err := Decode(strings.NewReader("not json"), nil); err == nil {
+		t.Error("expected an error")
+	}
+}
@@ -0,0 +1,65 @@
+// Copyright (c) 2026 The Gocov Authors.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// listFunctions prints the functions in the non-test source files of
+// the named packages, with their positions and number of statements,
+// in the same form as they would be reported by "gocov test". Any
+// flags preceding the packages, such as -tags, are passed to "go list".
+func listFunctions(args []string) error {
+	if err := checkGoTool(); err != nil {
+		return err
+	}
+	const format = `{{range .GoFiles}}{{$.ImportPath}}{{"\t"}}{{$.Dir}}{{"\t"}}{{.}}{{"\n"}}{{end}}`
+	var buf bytes.Buffer
+	cmdArgs := append([]string{"list", "-f", format}, args...)
+	cmd := exec.Command("go", cmdArgs...)
+	cmd.Stdout = &buf
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return err
+	}
+	for _, line := range strings.Split(buf.String(), "\n") {
+		fields := strings.Split(line, "\t")
+		if len(fields) != 3 {
+			continue
+		}
+		pkg, file := fields[0], filepath.Join(fields[1], fields[2])
+		extents, err := findFuncs(file)
+		if err != nil {
+			return err
+		}
+		for _, fe := range extents {
+			fmt.Printf("%s\t%s\t%s:%d.%d,%d.%d\t%d\n", pkg, fe.name, file,
+				fe.startLine, fe.startCol, fe.endLine, fe.endCol, len(fe.stmts))
+		}
+	}
+	return nil
+}
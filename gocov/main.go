@@ -21,37 +21,81 @@
 package main
 
 import (
+	"bytes"
 	"encoding/json"
 	"flag"
 	"fmt"
+	"io"
 	"os"
 
 	"github.com/axw/gocov"
+	"github.com/axw/gocov/gocovutil"
 )
 
 func usage() {
 	fmt.Fprintf(os.Stderr, "Usage:\n\n\tgocov command [arguments]\n\n")
 	fmt.Fprintf(os.Stderr, "The commands are:\n\n")
 	fmt.Fprintf(os.Stderr, "\tannotate\n")
+	fmt.Fprintf(os.Stderr, "\tclean\n")
+	fmt.Fprintf(os.Stderr, "\tcontribution\n")
 	fmt.Fprintf(os.Stderr, "\tconvert\n")
+	fmt.Fprintf(os.Stderr, "\tlist\n")
 	fmt.Fprintf(os.Stderr, "\treport\n")
+	fmt.Fprintf(os.Stderr, "\trun\n")
 	fmt.Fprintf(os.Stderr, "\ttest\n")
+	fmt.Fprintf(os.Stderr, "\ttrend\n")
 	fmt.Fprintf(os.Stderr, "\n")
 	flag.PrintDefaults()
 	os.Exit(2)
 }
 
+// exitEnvironment is the exit status of gocov when it cannot run in
+// its environment, such as when the go tool is missing, as opposed
+// to when the tests or gocov itself fail.
+const exitEnvironment = 3
+
+// environmentError is an error caused by gocov's environment.
+type environmentError struct {
+	error
+}
+
+// exitStatus returns the exit status for an error of a command.
+func exitStatus(err error) int {
+	if _, ok := err.(environmentError); ok {
+		return exitEnvironment
+	}
+	return 1
+}
+
 func marshalJson(packages []*gocov.Package) ([]byte, error) {
 	return json.Marshal(struct{ Packages []*gocov.Package }{packages})
 }
 
+// unmarshalJson parses one or more concatenated {"Packages": [...]}
+// records, such as those written by gocov test -append, accumulating
+// the coverage of packages that appear in more than one record.
 func unmarshalJson(data []byte) (packages []*gocov.Package, err error) {
-	result := &struct{ Packages []*gocov.Package }{}
-	err = json.Unmarshal(data, result)
-	if err == nil {
-		packages = result.Packages
+	var records [][]*gocov.Package
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	for {
+		result := &struct{ Packages []*gocov.Package }{}
+		if err := decoder.Decode(result); err == io.EOF && len(records) > 0 {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		records = append(records, result.Packages)
+	}
+	if len(records) == 1 {
+		return records[0], nil
 	}
-	return
+	var merged gocovutil.Packages
+	for _, record := range records {
+		for _, pkg := range record {
+			merged.AddPackage(pkg)
+		}
+	}
+	return merged, nil
 }
 
 func main() {
@@ -62,6 +106,11 @@ func main() {
 	if flag.NArg() > 0 {
 		command = flag.Arg(0)
 		switch command {
+		case "contribution":
+			if err := testContribution(flag.Args()[1:]); err != nil {
+				fmt.Fprintln(os.Stderr, "error:", err)
+				os.Exit(exitStatus(err))
+			}
 		case "convert":
 			if flag.NArg() <= 1 {
 				fmt.Fprintln(os.Stderr, "missing cover profile")
@@ -73,10 +122,30 @@ func main() {
 			}
 		case "annotate":
 			os.Exit(annotateSource())
+		case "clean":
+			if err := cleanTemp(flag.Args()[1:]); err != nil {
+				fmt.Fprintln(os.Stderr, "error:", err)
+				os.Exit(1)
+			}
+		case "list":
+			if err := listFunctions(flag.Args()[1:]); err != nil {
+				fmt.Fprintln(os.Stderr, "error:", err)
+				os.Exit(exitStatus(err))
+			}
 		case "report":
 			os.Exit(reportCoverage())
+		case "run":
+			if err := runProgram(flag.Args()[1:]); err != nil {
+				fmt.Fprintln(os.Stderr, "error:", err)
+				os.Exit(exitStatus(err))
+			}
 		case "test":
 			if err := runTests(flag.Args()[1:]); err != nil {
+				fmt.Fprintln(os.Stderr, "error:", err)
+				os.Exit(exitStatus(err))
+			}
+		case "trend":
+			if err := trendCoverage(flag.Args()[1:]); err != nil {
 				fmt.Fprintln(os.Stderr, "error:", err)
 				os.Exit(1)
 			}
@@ -0,0 +1,107 @@
+// Copyright (c) 2026 The Gocov Authors.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package main
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"strconv"
+
+	"github.com/axw/gocov"
+)
+
+// platformChecks finds the comparisons of runtime.GOOS or
+// runtime.GOARCH in source files, to mark functions with branches that
+// may be unreachable on the current platform, for -platform.
+type platformChecks map[string]platformFile
+
+// platformFile holds the offsets of the platform checks found in a
+// file, or the error reading or parsing it, so that each file is
+// parsed only once.
+type platformFile struct {
+	offsets []int
+	err     error
+}
+
+// conditional reports whether fn compares runtime.GOOS or
+// runtime.GOARCH, in an expression or as the tag of a switch.
+func (p platformChecks) conditional(fn *gocov.Function) (bool, error) {
+	f, ok := p[fn.File]
+	if !ok {
+		f.offsets, f.err = findPlatformChecks(fn.File)
+		p[fn.File] = f
+	}
+	if f.err != nil {
+		return false, f.err
+	}
+	for _, offset := range f.offsets {
+		if offset >= fn.Start && offset < fn.End {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// findPlatformChecks returns the offsets of the comparisons of
+// runtime.GOOS or runtime.GOARCH in the named file.
+func findPlatformChecks(filename string) ([]int, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, filename, nil, 0)
+	if err != nil {
+		return nil, err
+	}
+	runtimeName := ""
+	for _, spec := range file.Imports {
+		if path, _ := strconv.Unquote(spec.Path.Value); path == "runtime" {
+			runtimeName = "runtime"
+			if spec.Name != nil {
+				runtimeName = spec.Name.Name
+			}
+		}
+	}
+	if runtimeName == "" {
+		return nil, nil
+	}
+	isPlatform := func(expr ast.Expr) bool {
+		sel, ok := expr.(*ast.SelectorExpr)
+		if !ok || (sel.Sel.Name != "GOOS" && sel.Sel.Name != "GOARCH") {
+			return false
+		}
+		x, ok := sel.X.(*ast.Ident)
+		return ok && x.Name == runtimeName
+	}
+	var offsets []int
+	ast.Inspect(file, func(node ast.Node) bool {
+		switch n := node.(type) {
+		case *ast.BinaryExpr:
+			if (n.Op == token.EQL || n.Op == token.NEQ) && (isPlatform(n.X) || isPlatform(n.Y)) {
+				offsets = append(offsets, position(fset, n.Pos()).Offset)
+			}
+		case *ast.SwitchStmt:
+			if n.Tag != nil && isPlatform(n.Tag) {
+				offsets = append(offsets, position(fset, n.Pos()).Offset)
+			}
+		}
+		return true
+	})
+	return offsets, nil
+}
@@ -0,0 +1,139 @@
+// Copyright (c) 2026 The Gocov Authors.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+package main
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+
+	"github.com/axw/gocov"
+)
+
+// recoveryPaths finds the statements in source files that run when a
+// panic is recovered, to report the coverage of panic recovery for
+// -recovery-paths.
+type recoveryPaths map[string]fileRanges
+
+// contains reports whether stmt, of fn, is on a recovery path.
+func (p recoveryPaths) contains(fn *gocov.Function, stmt *gocov.Statement) (bool, error) {
+	f, ok := p[fn.File]
+	if !ok {
+		f.ranges, f.err = findRecoveryPaths(fn.File)
+		p[fn.File] = f
+	}
+	if f.err != nil {
+		return false, f.err
+	}
+	for _, r := range f.ranges {
+		if stmt.Start >= r.start && stmt.Start < r.end {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// isRecoverCall reports whether expr is a call of the builtin recover.
+func isRecoverCall(expr ast.Expr) bool {
+	call, ok := expr.(*ast.CallExpr)
+	if !ok || len(call.Args) != 0 {
+		return false
+	}
+	ident, ok := call.Fun.(*ast.Ident)
+	return ok && ident.Name == "recover" && ident.Obj == nil
+}
+
+// findRecoveryPaths returns the extents of the recovery paths in the
+// named file. The blocks of if statements comparing the result of
+// recover with nil, such as "if r := recover(); r != nil", run only
+// when a panic was recovered. A call of recover whose result is not
+// checked, such as "defer func() { recover() }()", cannot be told
+// apart from a deferred call that recovered nothing, so the statement
+// calling it is taken as the recovery path.
+func findRecoveryPaths(filename string) ([]offsetRange, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, filename, nil, 0)
+	if err != nil {
+		return nil, err
+	}
+	extent := func(from, to token.Pos) offsetRange {
+		return offsetRange{
+			start: position(fset, from).Offset,
+			end:   position(fset, to).Offset,
+		}
+	}
+	// Variables assigned the result of recover, and the statements
+	// assigning them.
+	recovered := make(map[*ast.Object]ast.Stmt)
+	checked := make(map[*ast.Object]bool)
+	isRecovered := func(expr ast.Expr) bool {
+		if isRecoverCall(expr) {
+			return true
+		}
+		ident, ok := expr.(*ast.Ident)
+		if !ok || ident.Obj == nil {
+			return false
+		}
+		if _, ok := recovered[ident.Obj]; ok {
+			checked[ident.Obj] = true
+			return true
+		}
+		return false
+	}
+	isNil := func(expr ast.Expr) bool {
+		ident, ok := expr.(*ast.Ident)
+		return ok && ident.Name == "nil"
+	}
+	var ranges []offsetRange
+	ast.Inspect(file, func(node ast.Node) bool {
+		stmt, ok := node.(*ast.AssignStmt)
+		if ok && len(stmt.Lhs) == 1 && len(stmt.Rhs) == 1 && isRecoverCall(stmt.Rhs[0]) {
+			if ident, ok := stmt.Lhs[0].(*ast.Ident); ok && ident.Obj != nil {
+				recovered[ident.Obj] = stmt
+			} else {
+				ranges = append(ranges, extent(stmt.Pos(), stmt.End()))
+			}
+		}
+		return true
+	})
+	ast.Inspect(file, func(node ast.Node) bool {
+		switch stmt := node.(type) {
+		case *ast.ExprStmt:
+			if isRecoverCall(stmt.X) {
+				ranges = append(ranges, extent(stmt.Pos(), stmt.End()))
+			}
+		case *ast.IfStmt:
+			cond, ok := stmt.Cond.(*ast.BinaryExpr)
+			if !ok || cond.Op != token.NEQ {
+				break
+			}
+			if (isRecovered(cond.X) && isNil(cond.Y)) || (isNil(cond.X) && isRecovered(cond.Y)) {
+				ranges = append(ranges, extent(stmt.Body.Lbrace, stmt.Body.Rbrace+1))
+			}
+		}
+		return true
+	})
+	for obj, stmt := range recovered {
+		if !checked[obj] {
+			ranges = append(ranges, extent(stmt.Pos(), stmt.End()))
+		}
+	}
+	return ranges, nil
+}
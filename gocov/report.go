@@ -21,6 +21,9 @@
 package main
 
 import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
 	"flag"
 	"fmt"
 	"io"
@@ -28,14 +31,161 @@ import (
 	"os"
 	"path/filepath"
 	"sort"
+	"strconv"
 	"strings"
 	"text/tabwriter"
+	"time"
 
 	"github.com/axw/gocov"
 )
 
+var (
+	reportFlags   = flag.NewFlagSet("report", flag.ExitOnError)
+	reportRelFlag = reportFlags.Bool(
+		"rel", false,
+		"Print package names relative to the module path found in go.mod")
+	reportByFlag = reportFlags.String(
+		"by", "function",
+		`Group coverage within each package by "function" or "file", `+
+			`show whether each package is covered at all with "package-presence", `+
+			`group coverage by the owners given by -codeowners with "owner", `+
+			`or by the types implementing -interface with "interface"`)
+	reportCodeOwnersFlag = reportFlags.String(
+		"codeowners", "",
+		"CODEOWNERS file giving the owners of files for -by owner")
+	reportInterfaceFlag = reportFlags.String(
+		"interface", "",
+		"Interface, such as io.Reader, whose implementations are reported by -by interface")
+	reportFormatFlag = reportFlags.String(
+		"format", "text",
+		"Output format of the report; see -list-formats")
+	reportListFormatsFlag = reportFlags.Bool(
+		"list-formats", false,
+		"List the formats accepted by -format, and exit")
+	reportHistogramFlag = reportFlags.Bool(
+		"histogram", false,
+		"Include a histogram of the number of times statements were reached")
+	reportLinesFlag = reportFlags.String(
+		"lines", "",
+		"Report only statements within the line ranges listed in the named file, one file:start-end per line")
+	reportTemplateFlag = reportFlags.String(
+		"template", "",
+		"Template file executed by -format template")
+	reportExportedOnlyFlag = reportFlags.Bool(
+		"exported-only", false,
+		"Report only exported functions, and methods of exported types")
+	reportRequireStatementsFlag = reportFlags.Bool(
+		"require-statements", false,
+		"Fail if there are no statements to report, such as when -lines matches nothing")
+	reportPlatformFlag = reportFlags.Bool(
+		"platform", false,
+		"Mark functions that compare runtime.GOOS or runtime.GOARCH, whose coverage depends on the platform")
+	reportErrorPathsFlag = reportFlags.Bool(
+		"error-paths", false,
+		"Also report the coverage of the statements within \"if err != nil\" blocks")
+	reportRecoveryPathsFlag = reportFlags.Bool(
+		"recovery-paths", false,
+		"Also report the coverage of the statements that run when recover() returns a panic")
+	reportBadgeColorsFlag = reportFlags.String(
+		"badge-colors", "50,80",
+		"Coverage percentages at which -format shields turns from red to yellow, and from yellow to green")
+	reportShowSourceOnMissFlag = reportFlags.Int(
+		"show-source-on-miss", 0,
+		"Print the source of up to this many statements that were not reached, after the text report")
+	reportMaxAnnotationsFlag = reportFlags.Int(
+		"max-annotations", 10,
+		"Maximum number of statements annotated by -format github-actions, or 0 for no limit")
+	reportCSVHeaderFlag = reportFlags.Bool(
+		"csv-header", true,
+		"Write a header row with -format csv")
+	reportGroupDepthFlag = reportFlags.Int(
+		"group-depth", 0,
+		"Combine the coverage of packages sharing their first N import path elements, reporting one line for each group")
+	reportLineAttributionFlag = reportFlags.String(
+		"line-attribution", "all",
+		`Lines of a statement marked covered by the line-based sonarqube format: "all" the lines it spans, or "first" only its first`)
+	reportTrendFileFlag = reportFlags.String(
+		"trend-file", "",
+		"Append the time, total coverage and git commit to this CSV file, to be shown by gocov trend")
+	reportOutputFlag = reportFlags.String(
+		"o", "-",
+		"File to which to write the report, or \"-\" for stdout")
+	reportDeltaFlag = reportFlags.Bool(
+		"delta", false,
+		"Print the change in total coverage since the previous run, recorded in "+lastCoverageFile+
+			"; only supported by the text format")
+)
+
 type report struct {
 	packages []*gocov.Package
+
+	// modulePath, if non-empty, is stripped from the
+	// beginning of package names when printing.
+	modulePath string
+
+	// by determines how coverage is grouped within each
+	// package: by "function" or by "file". If by is
+	// "package-presence", each package is only reported as
+	// covered or not covered. If by is "owner", coverage is
+	// grouped across packages by the owners of each file, and if
+	// by is "interface", by the types implementing an interface.
+	by string
+
+	// owners holds the rules of the CODEOWNERS file that
+	// determine the owners of files with -by owner.
+	owners *codeOwners
+
+	// implementations holds the types implementing the interface
+	// given by -interface, with -by interface.
+	implementations []implementation
+
+	// lineAttribution determines the lines to which line-based
+	// formats attribute a statement: "all" or "first".
+	lineAttribution string
+
+	// groupDepth, if positive, is the number of leading import path
+	// elements by which packages are grouped, each group being
+	// reported in place of its packages.
+	groupDepth int
+
+	// previous, if non-nil, is the total coverage recorded by
+	// the previous run for the same set of packages.
+	previous *float64
+
+	// histogram determines whether a histogram of the number of
+	// times statements were reached is included.
+	histogram bool
+
+	// template is the name of the text/template file executed by
+	// the "template" format.
+	template string
+
+	// badgeColors holds the coverage percentages at which the
+	// "shields" format turns from red to yellow, and yellow to green.
+	badgeColors [2]float64
+
+	// platform, if non-nil, is used to mark functions with
+	// branches that depend on the platform.
+	platform platformChecks
+
+	// errorPaths, if non-nil, is used to report the coverage of
+	// the statements that handle errors.
+	errorPaths errorPaths
+
+	// recoveryPaths, if non-nil, is used to report the coverage of
+	// the statements that handle recovered panics.
+	recoveryPaths recoveryPaths
+
+	// maxAnnotations is the maximum number of statements annotated
+	// by the "github-actions" format, or 0 if there is no limit.
+	maxAnnotations int
+
+	// csvHeader is whether the "csv" format writes a header row.
+	csvHeader bool
+
+	// missedSource is the number of statements that were not
+	// reached whose source is printed after the text report.
+	missedSource int
 }
 
 type reportFunction struct {
@@ -101,6 +251,63 @@ func (r *report) clear() {
 	r.packages = nil
 }
 
+// packageName returns the name of the package as it should be
+// displayed in the report.
+func (r *report) packageName(name string) string {
+	if r.modulePath == "" {
+		return name
+	}
+	return relativePackageName(r.modulePath, name)
+}
+
+// relativePackageName returns the package name relative to the
+// module path. Packages outside of the module are returned
+// unchanged, and the module's root package is returned as ".".
+func relativePackageName(modulePath, name string) string {
+	if name == modulePath {
+		return "."
+	}
+	if strings.HasPrefix(name, modulePath+"/") {
+		return name[len(modulePath)+1:]
+	}
+	return name
+}
+
+// findModulePath searches dir and each of its parents for a go.mod
+// file, and returns the module path declared within it.
+func findModulePath(dir string) (string, error) {
+	for {
+		data, err := ioutil.ReadFile(filepath.Join(dir, "go.mod"))
+		if err == nil {
+			return parseModulePath(data)
+		}
+		if !os.IsNotExist(err) {
+			return "", err
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", fmt.Errorf("go.mod not found")
+		}
+		dir = parent
+	}
+}
+
+// parseModulePath returns the module path declared in the
+// contents of a go.mod file.
+func parseModulePath(data []byte) (string, error) {
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) >= 2 && fields[0] == "module" {
+			path := fields[1]
+			if unquoted, err := strconv.Unquote(path); err == nil {
+				path = unquoted
+			}
+			return path, nil
+		}
+	}
+	return "", fmt.Errorf("no module directive found in go.mod")
+}
+
 // functionReports returns the packages functions as an array of
 // reportFunction objects with the statements reached calculated
 func functionReports(pkg *gocov.Package) reportFunctionList {
@@ -119,40 +326,397 @@ func functionReports(pkg *gocov.Package) reportFunctionList {
 
 }
 
-// printTotalCoverage outputs the combined coverage for each
-// package
-func (r *report) printTotalCoverage(w io.Writer) {
-	var totalStatements, totalReached int
+// reportFile records the coverage of the statements in a file.
+type reportFile struct {
+	name              string
+	statements        int
+	statementsReached int
+}
 
+// fileReports returns the coverage of each file containing the
+// package's functions, ordered by file name. Files are grouped
+// within the package only, so a file that somehow contributes
+// functions to more than one package is counted in each.
+func fileReports(pkg *gocov.Package) []reportFile {
+	var files []reportFile
+	index := make(map[string]int)
+	for _, fn := range functionReports(pkg) {
+		i, ok := index[fn.File]
+		if !ok {
+			i = len(files)
+			index[fn.File] = i
+			files = append(files, reportFile{name: fn.File})
+		}
+		files[i].statements += len(fn.Statements)
+		files[i].statementsReached += fn.statementsReached
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].name < files[j].name
+	})
+	return files
+}
+
+// reportOwner records the coverage of the statements owned by an
+// owner, or set of owners, of a CODEOWNERS file.
+type reportOwner struct {
+	name              string
+	statements        int
+	statementsReached int
+}
+
+// ownerReports returns the coverage of the statements of each owner
+// given by r.owners, across all packages, ordered by owner.
+func (r *report) ownerReports() []reportOwner {
+	var owners []reportOwner
+	index := make(map[string]int)
 	for _, pkg := range r.packages {
-		functions := functionReports(pkg)
-		sort.Sort(reverse{functions})
+		for _, fn := range functionReports(pkg) {
+			owner := r.owners.owner(fn.File)
+			i, ok := index[owner]
+			if !ok {
+				i = len(owners)
+				index[owner] = i
+				owners = append(owners, reportOwner{name: owner})
+			}
+			owners[i].statements += len(fn.Statements)
+			owners[i].statementsReached += fn.statementsReached
+		}
+	}
+	sort.Slice(owners, func(i, j int) bool {
+		return owners[i].name < owners[j].name
+	})
+	return owners
+}
 
-		for _, fn := range functions {
-			reached := fn.statementsReached
+// groupReports returns the coverage of the packages grouped by the
+// first r.groupDepth elements of their import paths, ordered by group.
+// Packages with fewer elements form groups of their own.
+func (r *report) groupReports() []reportOwner {
+	var groups []reportOwner
+	index := make(map[string]int)
+	for _, pkg := range r.packages {
+		name := pkg.Name
+		if elems := strings.Split(name, "/"); len(elems) > r.groupDepth {
+			name = strings.Join(elems[:r.groupDepth], "/")
+		}
+		i, ok := index[name]
+		if !ok {
+			i = len(groups)
+			index[name] = i
+			groups = append(groups, reportOwner{name: name})
+		}
+		for _, fn := range functionReports(pkg) {
+			groups[i].statements += len(fn.Statements)
+			groups[i].statementsReached += fn.statementsReached
+		}
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		return groups[i].name < groups[j].name
+	})
+	return groups
+}
+
+// implementationReports returns the coverage of the methods with which
+// each of r.implementations implements the interface, ordered by type.
+func (r *report) implementationReports() []reportOwner {
+	functions := make(map[methodFunction]reportFunction)
+	for _, pkg := range r.packages {
+		for _, fn := range functionReports(pkg) {
+			functions[methodFunction{pkg.Name, fn.Name}] = fn
+		}
+	}
+	var implementations []reportOwner
+	for _, impl := range r.implementations {
+		implementation := reportOwner{name: impl.name}
+		for _, method := range impl.methods {
+			if fn, ok := functions[method]; ok {
+				implementation.statements += len(fn.Statements)
+				implementation.statementsReached += fn.statementsReached
+			}
+		}
+		implementations = append(implementations, implementation)
+	}
+	return implementations
+}
+
+// totals returns the total number of statements in the report,
+// and the number of those that were reached.
+func (r *report) totals() (totalStatements, totalReached int) {
+	for _, pkg := range r.packages {
+		for _, fn := range functionReports(pkg) {
 			totalStatements += len(fn.Statements)
-			totalReached += reached
+			totalReached += fn.statementsReached
+		}
+	}
+	return totalStatements, totalReached
+}
+
+// errorPathTotals returns the number of statements in the report that
+// are within the blocks of error checks, and the number of those that
+// were reached. Files that cannot be parsed are taken to have none.
+func (r *report) errorPathTotals() (statements, reached int) {
+	for _, pkg := range r.packages {
+		for _, fn := range pkg.Functions {
+			for _, stmt := range fn.Statements {
+				if ok, _ := r.errorPaths.contains(fn, stmt); !ok {
+					continue
+				}
+				statements++
+				if stmt.Reached > 0 {
+					reached++
+				}
+			}
 		}
 	}
+	return statements, reached
+}
 
+// recoveryPathTotals returns the number of statements in the report
+// that are on recovery paths, and the number of those that were
+// reached. Files that cannot be parsed are taken to have none.
+func (r *report) recoveryPathTotals() (statements, reached int) {
+	for _, pkg := range r.packages {
+		for _, fn := range pkg.Functions {
+			for _, stmt := range fn.Statements {
+				if ok, _ := r.recoveryPaths.contains(fn, stmt); !ok {
+					continue
+				}
+				statements++
+				if stmt.Reached > 0 {
+					reached++
+				}
+			}
+		}
+	}
+	return statements, reached
+}
+
+// printTotalCoverage outputs the combined coverage for each
+// package
+func (r *report) printTotalCoverage(w io.Writer) {
+	totalStatements, totalReached := r.totals()
 	coveragePercentage := float64(totalReached) / float64(totalStatements) * 100
 	fmt.Fprintf(w, "Total Coverage: %.2f%% (%d/%d)", coveragePercentage, totalReached, totalStatements)
+	if r.previous != nil {
+		delta := percent(totalReached, totalStatements) - *r.previous
+		switch {
+		case delta > 0:
+			fmt.Fprintf(w, " \u2191 %+.2f%%", delta)
+		case delta < 0:
+			fmt.Fprintf(w, " \u2193 %+.2f%%", delta)
+		default:
+			fmt.Fprintf(w, " (unchanged)")
+		}
+	}
 	fmt.Fprintln(w)
 }
 
+// lastCoverageFile is the file in which the total coverage of each
+// set of packages is recorded by "gocov report -delta".
+const lastCoverageFile = ".gocov-last"
+
+// packageSetKey returns a key identifying the set of packages in
+// the report.
+func (r *report) packageSetKey() string {
+	h := sha1.New()
+	for _, pkg := range r.packages {
+		fmt.Fprintln(h, pkg.Name)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// updateLastCoverage records the total coverage of the report in the
+// named file, and returns the coverage previously recorded for the
+// same set of packages, or nil if there is none.
+func (r *report) updateLastCoverage(filename string) (*float64, error) {
+	last := make(map[string]float64)
+	data, err := ioutil.ReadFile(filename)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if err == nil {
+		if err := json.Unmarshal(data, &last); err != nil {
+			return nil, fmt.Errorf("%s: %v", filename, err)
+		}
+	}
+	key := r.packageSetKey()
+	var previous *float64
+	if value, ok := last[key]; ok {
+		previous = &value
+	}
+	totalStatements, totalReached := r.totals()
+	last[key] = percent(totalReached, totalStatements)
+	if data, err = json.Marshal(last); err != nil {
+		return nil, err
+	}
+	return previous, writeFileAtomic(filename, data)
+}
+
+// writeFileAtomic replaces the named file with data by renaming a
+// temporary file over it, so that other gocov processes recording
+// their coverage at the same time never read a partly written file.
+// Their updates may still replace each other's.
+func writeFileAtomic(filename string, data []byte) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(filename), filepath.Base(filename)+".tmp*")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(tmp.Name(), 0644)
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), filename)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+	return err
+}
+
 // PrintReport prints a coverage report to the given writer.
 func printReport(w io.Writer, r *report) {
 	w = tabwriter.NewWriter(w, 0, 8, 0, '\t', 0)
 	//fmt.Fprintln(w, "Package\tFunction\tStatements\t")
 	//fmt.Fprintln(w, "-------\t--------\t---------\t")
-	for _, pkg := range r.packages {
-		printPackage(w, pkg)
+	if r.by == "owner" || r.by == "interface" || r.groupDepth > 0 {
+		groups := r.ownerReports
+		if r.by == "interface" {
+			groups = r.implementationReports
+		} else if r.groupDepth > 0 {
+			groups = r.groupReports
+		}
+		for _, group := range groups() {
+			name := group.name
+			if r.groupDepth > 0 {
+				name = r.packageName(name)
+			}
+			fmt.Fprintf(w, "%s\t %.2f%% (%d/%d)\n", name,
+				percent(group.statementsReached, group.statements),
+				group.statementsReached, group.statements)
+		}
+		fmt.Fprintln(w)
+	} else {
+		for _, pkg := range r.packages {
+			r.printPackage(w, pkg)
+			if r.by != "package-presence" {
+				fmt.Fprintln(w)
+			}
+		}
+	}
+	if r.by == "package-presence" {
+		covered, total := r.packagePresence()
 		fmt.Fprintln(w)
+		fmt.Fprintf(w, "Packages Covered: %.2f%% (%d/%d)\n", percent(covered, total), covered, total)
 	}
 	r.printTotalCoverage(w)
+	if r.errorPaths != nil {
+		statements, reached := r.errorPathTotals()
+		fmt.Fprintf(w, "Error Path Coverage: %.2f%% (%d/%d)\n", percent(reached, statements), reached, statements)
+	}
+	if r.recoveryPaths != nil {
+		statements, reached := r.recoveryPathTotals()
+		fmt.Fprintf(w, "Recovery Path Coverage: %.2f%% (%d/%d)\n", percent(reached, statements), reached, statements)
+	}
+	if r.histogram {
+		fmt.Fprintln(w)
+		printHistogram(w, r.hitHistogram())
+	}
+}
+
+// hitBucket records the number of statements that were reached a
+// number of times within a range.
+type hitBucket struct {
+	Label string
+
+	// Min and Max are the inclusive bounds of the range. A negative
+	// Max indicates that the range has no upper bound.
+	Min, Max int64
+
+	Statements int
+}
+
+// hitHistogram returns the number of statements in the report that
+// were reached zero times, once, 2-10 times, 11-100 times, and more
+// than 100 times. Coverage recorded with -covermode=set records only
+// whether a statement was reached, so will have no statements in the
+// larger buckets.
+func (r *report) hitHistogram() []hitBucket {
+	buckets := []hitBucket{
+		{Label: "0", Min: 0, Max: 0},
+		{Label: "1", Min: 1, Max: 1},
+		{Label: "2-10", Min: 2, Max: 10},
+		{Label: "11-100", Min: 11, Max: 100},
+		{Label: "100+", Min: 101, Max: -1},
+	}
+	for _, pkg := range r.packages {
+		for _, fn := range pkg.Functions {
+			for _, stmt := range fn.Statements {
+				for i := range buckets {
+					if stmt.Reached >= buckets[i].Min && (buckets[i].Max < 0 || stmt.Reached <= buckets[i].Max) {
+						buckets[i].Statements++
+						break
+					}
+				}
+			}
+		}
+	}
+	return buckets
+}
+
+// printHistogram prints the buckets as a bar chart.
+func printHistogram(w io.Writer, buckets []hitBucket) {
+	const width = 40
+	var max int
+	for _, b := range buckets {
+		if b.Statements > max {
+			max = b.Statements
+		}
+	}
+	fmt.Fprintln(w, "Statements by Times Reached:")
+	for _, b := range buckets {
+		var bar int
+		if max > 0 {
+			bar = (b.Statements*width + max - 1) / max
+		}
+		fmt.Fprintf(w, "%6s |%s %d\n", b.Label, strings.Repeat("#", bar), b.Statements)
+	}
 }
 
-func printPackage(w io.Writer, pkg *gocov.Package) {
+// packageStatements returns the number of statements in the package,
+// and the number of those that were reached.
+func packageStatements(pkg *gocov.Package) (statements, reached int) {
+	for _, fn := range functionReports(pkg) {
+		statements += len(fn.Statements)
+		reached += fn.statementsReached
+	}
+	return statements, reached
+}
+
+// packagePresence returns the number of packages in the report with
+// at least one statement reached, and the total number of packages.
+func (r *report) packagePresence() (covered, total int) {
+	for _, pkg := range r.packages {
+		if _, reached := packageStatements(pkg); reached > 0 {
+			covered++
+		}
+	}
+	return covered, len(r.packages)
+}
+
+func (r *report) printPackage(w io.Writer, pkg *gocov.Package) {
+	switch r.by {
+	case "file":
+		r.printPackageFiles(w, pkg)
+		return
+	case "package-presence":
+		r.printPackagePresence(w, pkg)
+		return
+	}
+	name := r.packageName(pkg.Name)
 	functions := functionReports(pkg)
 	sort.Sort(reverse{functions})
 
@@ -169,9 +733,16 @@ func printPackage(w io.Writer, pkg *gocov.Package) {
 		if len(fn.Name) > longestFunctionName {
 			longestFunctionName = len(fn.Name)
 		}
-		fmt.Fprintf(w, "%s/%s\t %s\t %.2f%% (%d/%d)\n",
-			pkg.Name, filepath.Base(fn.File), fn.Name, stmtPercent,
-			reached, len(fn.Statements))
+		var note string
+		if r.platform != nil {
+			// Source that cannot be parsed is not marked.
+			if conditional, _ := r.platform.conditional(fn.Function); conditional {
+				note = " (platform-conditional)"
+			}
+		}
+		fmt.Fprintf(w, "%s/%s\t %s\t %.2f%% (%d/%d)%s\n",
+			name, filepath.Base(fn.File), fn.Name, stmtPercent,
+			reached, len(fn.Statements), note)
 	}
 
 	var funcPercent float64
@@ -180,25 +751,157 @@ func printPackage(w io.Writer, pkg *gocov.Package) {
 	}
 	summaryLine := strings.Repeat("-", longestFunctionName)
 	fmt.Fprintf(w, "%s\t %s\t %.2f%% (%d/%d)\n",
-		pkg.Name, summaryLine, funcPercent,
+		name, summaryLine, funcPercent,
 		totalReached, totalStatements)
 }
 
+func (r *report) printPackageFiles(w io.Writer, pkg *gocov.Package) {
+	name := r.packageName(pkg.Name)
+	var totalStatements, totalReached int
+	for _, file := range fileReports(pkg) {
+		totalStatements += file.statements
+		totalReached += file.statementsReached
+		fmt.Fprintf(w, "%s/%s\t %.2f%% (%d/%d)\n",
+			name, filepath.Base(file.name),
+			percent(file.statementsReached, file.statements),
+			file.statementsReached, file.statements)
+	}
+	fmt.Fprintf(w, "%s\t %.2f%% (%d/%d)\n",
+		name, percent(totalReached, totalStatements),
+		totalReached, totalStatements)
+}
+
+func (r *report) printPackagePresence(w io.Writer, pkg *gocov.Package) {
+	statements, reached := packageStatements(pkg)
+	presence := "not covered"
+	if reached > 0 {
+		presence = "covered"
+	}
+	fmt.Fprintf(w, "%s\t %s\t %.2f%% (%d/%d)\n",
+		r.packageName(pkg.Name), presence,
+		percent(reached, statements), reached, statements)
+}
+
+// percent returns n as a percentage of total,
+// or zero if total is zero.
+func percent(n, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(n) / float64(total) * 100
+}
+
 func reportCoverage() (rc int) {
+	if err := applyConfig(reportFlags); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to apply configuration: %s\n", err)
+		return 1
+	}
+	reportFlags.Parse(os.Args[2:])
+	if *reportListFormatsFlag {
+		printReportFormats(os.Stdout)
+		return 0
+	}
 	files := make([]*os.File, 0, 1)
-	if flag.NArg() > 1 {
-		for _, name := range flag.Args()[1:] {
-			file, err := os.Open(name)
-			if err != nil {
-				fmt.Fprintf(os.Stderr, "failed to open file (%s): %s\n", name, err)
-			} else {
-				files = append(files, file)
+	if reportFlags.NArg() > 0 {
+		for _, name := range reportFlags.Args() {
+			// A directory, such as one written by gocov test
+			// -split-output, holds a coverage file per package.
+			names := []string{name}
+			if info, err := os.Stat(name); err == nil && info.IsDir() {
+				names, _ = filepath.Glob(filepath.Join(name, "*.json"))
+			}
+			for _, name := range names {
+				file, err := os.Open(name)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "failed to open file (%s): %s\n", name, err)
+				} else {
+					files = append(files, file)
+				}
 			}
 		}
 	} else {
 		files = append(files, os.Stdin)
 	}
+	formatter, ok := reportFormats[*reportFormatFlag]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown report format %q; valid formats are: %s\n",
+			*reportFormatFlag, strings.Join(reportFormatNames(), ", "))
+		return 1
+	}
+	switch *reportByFlag {
+	case "function", "file", "package-presence", "owner", "interface":
+	default:
+		fmt.Fprintf(os.Stderr, "invalid -by value %q\n", *reportByFlag)
+		return 1
+	}
 	report := newReport()
+	report.by = *reportByFlag
+	if report.by == "owner" {
+		if *reportCodeOwnersFlag == "" {
+			fmt.Fprintln(os.Stderr, "-by owner requires -codeowners")
+			return 1
+		}
+		owners, err := readCodeOwners(*reportCodeOwnersFlag)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to read CODEOWNERS: %s\n", err)
+			return 1
+		}
+		report.owners = owners
+	}
+	if *reportGroupDepthFlag < 0 {
+		fmt.Fprintf(os.Stderr, "invalid -group-depth %d\n", *reportGroupDepthFlag)
+		return 1
+	}
+	if *reportGroupDepthFlag > 0 && report.by != "function" && report.by != "file" {
+		fmt.Fprintf(os.Stderr, "-group-depth cannot be used with -by %s\n", report.by)
+		return 1
+	}
+	report.groupDepth = *reportGroupDepthFlag
+	switch *reportLineAttributionFlag {
+	case "all", "first":
+	default:
+		fmt.Fprintf(os.Stderr, "invalid -line-attribution value %q\n", *reportLineAttributionFlag)
+		return 1
+	}
+	report.lineAttribution = *reportLineAttributionFlag
+	if report.by == "interface" && *reportInterfaceFlag == "" {
+		fmt.Fprintln(os.Stderr, "-by interface requires -interface")
+		return 1
+	}
+	report.histogram = *reportHistogramFlag
+	report.template = *reportTemplateFlag
+	report.maxAnnotations = *reportMaxAnnotationsFlag
+	report.csvHeader = *reportCSVHeaderFlag
+	report.missedSource = *reportShowSourceOnMissFlag
+	if report.missedSource > 0 && *reportFormatFlag != "text" {
+		fmt.Fprintf(os.Stderr, "-show-source-on-miss is not supported by the %q format\n", *reportFormatFlag)
+		return 1
+	}
+	if *reportPlatformFlag {
+		report.platform = make(platformChecks)
+	}
+	if *reportErrorPathsFlag {
+		report.errorPaths = make(errorPaths)
+	}
+	if *reportRecoveryPathsFlag {
+		report.recoveryPaths = make(recoveryPaths)
+	}
+	badgeColors, err := parseBadgeColors(*reportBadgeColorsFlag)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid -badge-colors: %s\n", err)
+		return 1
+	}
+	report.badgeColors = badgeColors
+	if *reportRelFlag {
+		wd, err := os.Getwd()
+		if err == nil {
+			report.modulePath, err = findModulePath(wd)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to determine module path: %s\n", err)
+			return 1
+		}
+	}
 	for _, file := range files {
 		data, err := ioutil.ReadAll(file)
 		if err != nil {
@@ -218,7 +921,77 @@ func reportCoverage() (rc int) {
 			file.Close()
 		}
 	}
-	fmt.Println()
-	printReport(os.Stdout, report)
+	if *reportLinesFlag != "" {
+		data, err := ioutil.ReadFile(*reportLinesFlag)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to read line ranges: %s\n", err)
+			return 1
+		}
+		ranges, err := parseLineRanges(data)
+		if err == nil {
+			report.packages, err = filterLines(report.packages, ranges)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to filter line ranges: %s\n", err)
+			return 1
+		}
+	}
+	if *reportExportedOnlyFlag {
+		report.packages = filterExported(report.packages)
+	}
+	if report.by == "interface" {
+		var pkgs []string
+		for _, pkg := range report.packages {
+			pkgs = append(pkgs, pkg.Name)
+		}
+		report.implementations, err = findImplementations(*reportInterfaceFlag, pkgs)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to find implementations of %s: %s\n", *reportInterfaceFlag, err)
+			return 1
+		}
+	}
+	if *reportRequireStatementsFlag {
+		if statements, _ := report.totals(); statements == 0 {
+			fmt.Fprintln(os.Stderr, "no statements to report; check the packages tested and any -lines ranges")
+			return 1
+		}
+	}
+	if *reportTrendFileFlag != "" {
+		if err := appendTrend(*reportTrendFileFlag, report, time.Now(), gitCommit()); err != nil {
+			fmt.Fprintf(os.Stderr, "failed to record coverage trend: %s\n", err)
+			return 1
+		}
+	}
+	if *reportDeltaFlag {
+		if *reportFormatFlag != "text" {
+			fmt.Fprintf(os.Stderr, "-delta is not supported by the %q format\n", *reportFormatFlag)
+			return 1
+		}
+		previous, err := report.updateLastCoverage(lastCoverageFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to record coverage: %s\n", err)
+			return 1
+		}
+		report.previous = previous
+	}
+	output := os.Stdout
+	if *reportOutputFlag != "-" {
+		output, err = os.Create(*reportOutputFlag)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to create report: %s\n", err)
+			return 1
+		}
+		defer output.Close()
+	}
+	if err := formatter.write(output, report); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to write report: %s\n", err)
+		return 1
+	}
+	if output != os.Stdout {
+		if err := output.Close(); err != nil {
+			fmt.Fprintf(os.Stderr, "failed to write report: %s\n", err)
+			return 1
+		}
+	}
 	return 0
 }
@@ -0,0 +1,957 @@
+// Copyright (c) 2026 The Gocov Authors.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/axw/gocov"
+)
+
+// newFunction returns a function with a statement for each of the
+// given reached counts.
+func newFunction(name, file string, reached ...int64) *gocov.Function {
+	fn := &gocov.Function{Name: name, File: file}
+	for i, n := range reached {
+		fn.Statements = append(fn.Statements, &gocov.Statement{
+			Start:   i * 10,
+			End:     i*10 + 5,
+			Reached: n,
+		})
+	}
+	fn.End = len(reached) * 10
+	return fn
+}
+
+// newTestReport returns a report containing the given packages.
+func newTestReport(packages ...*gocov.Package) *report {
+	r := newReport()
+	for _, pkg := range packages {
+		r.addPackage(pkg)
+	}
+	return r
+}
+
+func TestRelativePackageName(t *testing.T) {
+	const module = "github.com/me/project"
+	tests := []struct {
+		name, expect string
+	}{
+		{"github.com/me/project", "."},
+		{"github.com/me/project/internal/service/foo", "internal/service/foo"},
+		{"github.com/me/projectx/foo", "github.com/me/projectx/foo"},
+		{"github.com/other/dep", "github.com/other/dep"},
+	}
+	for _, test := range tests {
+		if name := relativePackageName(module, test.name); name != test.expect {
+			t.Errorf("relativePackageName(%q): got %q, expected %q", test.name, name, test.expect)
+		}
+	}
+}
+
+func TestFindModulePath(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gocov")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	gomod := []byte("// comment\nmodule \"github.com/me/project\"\n\ngo 1.12\n")
+	if err := ioutil.WriteFile(filepath.Join(dir, "go.mod"), gomod, 0644); err != nil {
+		t.Fatal(err)
+	}
+	subdir := filepath.Join(dir, "internal", "foo")
+	if err := os.MkdirAll(subdir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	path, err := findModulePath(subdir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if path != "github.com/me/project" {
+		t.Errorf("got %q, expected %q", path, "github.com/me/project")
+	}
+}
+
+func TestFileReports(t *testing.T) {
+	pkg := &gocov.Package{Name: "example.com/a", Functions: []*gocov.Function{
+		newFunction("F", "/src/a/a.go", 1, 0, 3),
+		newFunction("G", "/src/a/b.go", 0, 0),
+		newFunction("H", "/src/a/a.go", 2),
+	}}
+	files := fileReports(pkg)
+	expect := []reportFile{
+		{name: "/src/a/a.go", statements: 4, statementsReached: 3},
+		{name: "/src/a/b.go", statements: 2, statementsReached: 0},
+	}
+	if len(files) != len(expect) {
+		t.Fatalf("got %v, expected %v", files, expect)
+	}
+	var statements, reached int
+	for i, file := range files {
+		if file != expect[i] {
+			t.Errorf("got %v, expected %v", file, expect[i])
+		}
+		statements += file.statements
+		reached += file.statementsReached
+	}
+
+	r := newTestReport(pkg)
+	r.by = "file"
+	var buf bytes.Buffer
+	if err := writeJSONReport(&buf, r); err != nil {
+		t.Fatal(err)
+	}
+	var result jsonReport
+	if err := json.Unmarshal(buf.Bytes(), &result); err != nil {
+		t.Fatal(err)
+	}
+	jp := result.Packages[0]
+	if jp.Statements != statements || jp.Reached != reached {
+		t.Errorf("package total %d/%d does not match file totals %d/%d",
+			jp.Reached, jp.Statements, reached, statements)
+	}
+	if len(jp.Files) != 2 || len(jp.Functions) != 0 {
+		t.Errorf("expected 2 files and no functions, got %+v", jp)
+	}
+
+	buf.Reset()
+	printReport(&buf, r)
+	for _, line := range []string{
+		"example.com/a/a.go\t 75.00% (3/4)\n",
+		"example.com/a/b.go\t 0.00% (0/2)\n",
+		"example.com/a\t\t 50.00% (3/6)\n",
+	} {
+		if !strings.Contains(buf.String(), line) {
+			t.Errorf("expected %q in output:\n%s", line, buf.String())
+		}
+	}
+}
+
+func TestUncoveredReport(t *testing.T) {
+	r := newTestReport(testPackages(t, "./testdata/simple")...)
+	var buf bytes.Buffer
+	if err := writeUncoveredReport(&buf, r); err != nil {
+		t.Fatal(err)
+	}
+	file, err := filepath.Abs("testdata/simple/simple.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var expect string
+	for _, u := range []uncoveredRange{
+		{file, 7, 2, 7, 11},
+		{file, 11, 2, 11, 20},
+	} {
+		data, _ := json.Marshal(u)
+		expect += string(data) + "\n"
+	}
+	if buf.String() != expect {
+		t.Errorf("got:\n%s\nexpected:\n%s", buf.String(), expect)
+	}
+}
+
+func TestCoverageDelta(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gocov")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	lastFile := filepath.Join(dir, lastCoverageFile)
+
+	run := func(reached ...int64) string {
+		r := newTestReport(&gocov.Package{
+			Name:      "example.com/a",
+			Functions: []*gocov.Function{newFunction("F", "a.go", reached...)},
+		})
+		previous, err := r.updateLastCoverage(lastFile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r.previous = previous
+		var buf bytes.Buffer
+		r.printTotalCoverage(&buf)
+		return buf.String()
+	}
+	for _, test := range []struct {
+		reached []int64
+		expect  string
+	}{
+		{[]int64{1, 0, 0, 0}, "Total Coverage: 25.00% (1/4)\n"},
+		{[]int64{1, 1, 0, 0}, "Total Coverage: 50.00% (2/4) ↑ +25.00%\n"},
+		{[]int64{1, 1, 0, 0}, "Total Coverage: 50.00% (2/4) (unchanged)\n"},
+		{[]int64{0, 0, 0, 1}, "Total Coverage: 25.00% (1/4) ↓ -25.00%\n"},
+	} {
+		if got := run(test.reached...); got != test.expect {
+			t.Errorf("got %q, expected %q", got, test.expect)
+		}
+	}
+
+	// A different set of packages has no previous coverage.
+	r := newTestReport(&gocov.Package{Name: "example.com/b"})
+	if previous, err := r.updateLastCoverage(lastFile); err != nil || previous != nil {
+		t.Errorf("got (%v, %v), expected no previous coverage", previous, err)
+	}
+}
+
+func TestPackagePresence(t *testing.T) {
+	r := newTestReport(
+		&gocov.Package{Name: "example.com/a", Functions: []*gocov.Function{
+			newFunction("F", "a.go", 1, 0),
+		}},
+		&gocov.Package{Name: "example.com/b", Functions: []*gocov.Function{
+			newFunction("F", "b.go", 1),
+		}},
+		&gocov.Package{Name: "example.com/c", Functions: []*gocov.Function{
+			newFunction("F", "c.go", 0, 0, 0),
+		}},
+	)
+	if covered, total := r.packagePresence(); covered != 2 || total != 3 {
+		t.Errorf("got %d/%d, expected 2/3", covered, total)
+	}
+
+	r.by = "package-presence"
+	var buf bytes.Buffer
+	printReport(&buf, r)
+	for _, line := range []string{
+		"example.com/a\t covered\t 50.00% (1/2)\n",
+		"example.com/c\t not covered\t 0.00% (0/3)\n",
+		"Packages Covered: 66.67% (2/3)\n",
+	} {
+		if !strings.Contains(buf.String(), line) {
+			t.Errorf("expected %q in output:\n%s", line, buf.String())
+		}
+	}
+
+	buf.Reset()
+	if err := writeJSONReport(&buf, r); err != nil {
+		t.Fatal(err)
+	}
+	var result jsonReport
+	if err := json.Unmarshal(buf.Bytes(), &result); err != nil {
+		t.Fatal(err)
+	}
+	if result.PackagesCovered != 2 || len(result.Packages) != 3 {
+		t.Errorf("got %d/%d covered packages in JSON, expected 2/3",
+			result.PackagesCovered, len(result.Packages))
+	}
+}
+
+func TestHitHistogram(t *testing.T) {
+	r := newTestReport(&gocov.Package{Name: "example.com/a", Functions: []*gocov.Function{
+		newFunction("F", "a.go", 0, 0, 1, 2, 10, 11),
+		newFunction("G", "a.go", 100, 101, 5000, 0),
+	}})
+	r.histogram = true
+	expect := []int{3, 1, 2, 2, 2}
+	buckets := r.hitHistogram()
+	for i, b := range buckets {
+		if b.Statements != expect[i] {
+			t.Errorf("bucket %s: got %d statements, expected %d", b.Label, b.Statements, expect[i])
+		}
+	}
+
+	var buf bytes.Buffer
+	printReport(&buf, r)
+	for _, line := range []string{
+		"     0 |######################################## 3\n",
+		"     1 |############## 1\n",
+		"  100+ |########################### 2\n",
+	} {
+		if !strings.Contains(buf.String(), line) {
+			t.Errorf("expected %q in output:\n%s", line, buf.String())
+		}
+	}
+
+	buf.Reset()
+	if err := writeJSONReport(&buf, r); err != nil {
+		t.Fatal(err)
+	}
+	var result jsonReport
+	if err := json.Unmarshal(buf.Bytes(), &result); err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Histogram) != 5 || result.Histogram[4].Statements != 2 || result.Histogram[4].Max != -1 {
+		t.Errorf("unexpected histogram in JSON: %+v", result.Histogram)
+	}
+}
+
+func TestTemplateReport(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gocov")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	r := newTestReport(testPackages(t, "./testdata/simple")...)
+	r.template = filepath.Join(dir, "summary.tmpl")
+	tmpl := `{{len .Packages}} package(s), {{percent .Coverage}} covered` +
+		`{{range .Packages}}, least covered: {{(index (sortByCoverage .Functions) 0).Name}}{{end}}`
+	if err := ioutil.WriteFile(r.template, []byte(tmpl), 0644); err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := writeTemplateReport(&buf, r); err != nil {
+		t.Fatal(err)
+	}
+	expect := "1 package(s), 50.00% covered, least covered: Uncovered"
+	if buf.String() != expect {
+		t.Errorf("got %q, expected %q", buf.String(), expect)
+	}
+}
+
+// runReport runs "gocov report" with the given arguments, returning
+// its exit status and output.
+func runReport(t *testing.T, args ...string) (rc int, stdout, stderr string) {
+	defer resetFlags(reportFlags)
+	defer func(args []string) { os.Args = args }(os.Args)
+	os.Args = append([]string{"gocov", "report"}, args...)
+	stdout, stderr = captureOutput(t, func() { rc = reportCoverage() })
+	return rc, stdout, stderr
+}
+
+func TestReportListFormats(t *testing.T) {
+	rc, stdout, _ := runReport(t, "--list-formats")
+	if rc != 0 {
+		t.Fatalf("unexpected exit code %d", rc)
+	}
+	for _, name := range []string{"text", "json", "uncovered", "github-actions", "template"} {
+		if !strings.Contains(stdout, "\n"+name+" ") && !strings.HasPrefix(stdout, name+" ") {
+			t.Errorf("format %q not listed:\n%s", name, stdout)
+		}
+	}
+
+	// An unknown format is an error naming the valid ones.
+	rc, _, stderr := runReport(t, "-format", "htm")
+	if rc != 1 || !strings.Contains(stderr, `unknown report format "htm"; valid formats are: `) ||
+		!strings.Contains(stderr, "json, ") {
+		t.Errorf("unexpected exit code %d and error %q", rc, stderr)
+	}
+}
+
+func TestReportDeltaFormats(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gocov")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	data, err := marshalJson([]*gocov.Package{{
+		Name:      "example.com/a",
+		Functions: []*gocov.Function{newFunction("F", "a.go", 1, 0)},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile("coverage.json", data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	report := func(args ...string) (int, string) {
+		rc, _, stderr := runReport(t, args...)
+		return rc, stderr
+	}
+	recorded := func() bool {
+		_, err := os.Stat(lastCoverageFile)
+		return err == nil
+	}
+
+	// No coverage is recorded unless -delta is given, and then only
+	// by the text format, as other formats do not show the delta.
+	if rc, stderr := report("coverage.json"); rc != 0 || recorded() {
+		t.Errorf("text format: got %d, recorded %v\n%s", rc, recorded(), stderr)
+	}
+	if rc, stderr := report("-format", "json", "-delta", "coverage.json"); rc != 1 || !strings.Contains(stderr, "-delta is not supported") {
+		t.Errorf("-format json -delta: got %d\n%s", rc, stderr)
+	}
+	if rc, stderr := report("-delta", "coverage.json"); rc != 0 || !recorded() {
+		t.Errorf("text format -delta: got %d, recorded %v\n%s", rc, recorded(), stderr)
+	}
+}
+
+func TestReportRequireStatements(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gocov")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	coverage := filepath.Join(dir, "coverage.json")
+	data, err := marshalJson(testPackages(t, "./testdata/simple"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(coverage, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	lines := filepath.Join(dir, "lines")
+	if err := ioutil.WriteFile(lines, []byte("other.go:1-100\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	args := []string{"-require-statements", coverage}
+	if rc, _, stderr := runReport(t, args...); rc != 0 {
+		t.Errorf("got %d, expected 0\n%s", rc, stderr)
+	}
+	// Ranges matching nothing leave no statements.
+	args = append([]string{"-lines", lines}, args...)
+	if rc, _, stderr := runReport(t, args...); rc != 1 || !strings.Contains(stderr, "no statements to report") {
+		t.Errorf("got %d, expected 1\n%s", rc, stderr)
+	}
+}
+
+func TestReportPlatform(t *testing.T) {
+	r := newTestReport(testPackages(t, "./testdata/platform")...)
+	r.platform = make(platformChecks)
+	var buf bytes.Buffer
+	printReport(&buf, r)
+	for _, line := range strings.Split(buf.String(), "\n") {
+		marked := strings.HasSuffix(line, " (platform-conditional)")
+		switch {
+		case strings.Contains(line, " Separator\t"):
+			if !marked {
+				t.Errorf("Separator is not marked: %q", line)
+			}
+		case strings.Contains(line, " Join\t"):
+			if marked {
+				t.Errorf("Join is marked: %q", line)
+			}
+		}
+	}
+	if !strings.Contains(buf.String(), " Separator\t") {
+		t.Errorf("Separator is not reported:\n%s", buf.String())
+	}
+}
+
+func TestReportErrorPaths(t *testing.T) {
+	// Of the statements handling errors, the return in Parse is
+	// reached and the return in Double is not. The return of the
+	// check that n is negative is not an error path.
+	r := newTestReport(testPackages(t, "./testdata/errorpaths")...)
+	r.errorPaths = make(errorPaths)
+	if statements, reached := r.errorPathTotals(); statements != 2 || reached != 1 {
+		t.Errorf("got %d/%d error path statements reached, expected 1/2", reached, statements)
+	}
+	var buf bytes.Buffer
+	printReport(&buf, r)
+	if line := "Error Path Coverage: 50.00% (1/2)\n"; !strings.Contains(buf.String(), line) {
+		t.Errorf("expected %q in output:\n%s", line, buf.String())
+	}
+	if report := newJSONReport(r); report.ErrorPaths == nil || report.ErrorPaths.Coverage != 50 {
+		t.Errorf("unexpected error paths in JSON report: %+v", report.ErrorPaths)
+	}
+}
+
+func TestReportUnparsedFiles(t *testing.T) {
+	// A file that cannot be parsed is read only once: the error is
+	// returned again even once the file has been fixed.
+	file := filepath.Join(t.TempDir(), "bad.go")
+	if err := ioutil.WriteFile(file, []byte("package"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	fn := newFunction("F", file, 1)
+	errors, recoveries, platform := make(errorPaths), make(recoveryPaths), make(platformChecks)
+	for i := 0; i < 2; i++ {
+		if _, err := errors.contains(fn, fn.Statements[0]); err == nil {
+			t.Error("expected an error from errorPaths")
+		}
+		if _, err := recoveries.contains(fn, fn.Statements[0]); err == nil {
+			t.Error("expected an error from recoveryPaths")
+		}
+		if _, err := platform.conditional(fn); err == nil {
+			t.Error("expected an error from platformChecks")
+		}
+		if err := ioutil.WriteFile(file, []byte("package bad\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestReportRecoveryPaths(t *testing.T) {
+	// The panic recovered by Safe is handled, Must recovers nothing,
+	// and the unchecked recover in Quiet is reached whenever its
+	// deferred function runs.
+	r := newTestReport(testPackages(t, "./testdata/recoverypaths")...)
+	r.recoveryPaths = make(recoveryPaths)
+	if statements, reached := r.recoveryPathTotals(); statements != 3 || reached != 2 {
+		t.Errorf("got %d/%d recovery path statements reached, expected 2/3", reached, statements)
+	}
+	var buf bytes.Buffer
+	printReport(&buf, r)
+	if line := "Recovery Path Coverage: 66.67% (2/3)\n"; !strings.Contains(buf.String(), line) {
+		t.Errorf("expected %q in output:\n%s", line, buf.String())
+	}
+	if report := newJSONReport(r); report.RecoveryPaths == nil || report.RecoveryPaths.Reached != 2 {
+		t.Errorf("unexpected recovery paths in JSON report: %+v", report.RecoveryPaths)
+	}
+}
+
+func TestReportCSV(t *testing.T) {
+	// Parse is called by both TestParse and TestDouble, which between
+	// them reach all but the return of a negative number.
+	// Packages are named in full even with -rel.
+	r := newTestReport(testPackages(t, "-covermode=count", "./testdata/errorpaths")...)
+	r.csvHeader = true
+	r.modulePath = "github.com/axw/gocov"
+	var buf bytes.Buffer
+	if err := writeCSVReport(&buf, r); err != nil {
+		t.Fatal(err)
+	}
+	rows, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 3 || !reflect.DeepEqual(rows[0], csvHeader) {
+		t.Fatalf("unexpected rows: %q", rows)
+	}
+	expect := []string{
+		"github.com/axw/gocov/gocov/testdata/errorpaths", "Parse",
+		"testdata/errorpaths/errorpaths.go", "9", "6", "5", "83.33", "2",
+	}
+	if !reflect.DeepEqual(rows[1], expect) {
+		t.Errorf("got %q, expected %q", rows[1], expect)
+	}
+
+	// Names containing commas are quoted, and the header row may be
+	// left out.
+	file := filepath.Join(t.TempDir(), "a,b.go")
+	if err := ioutil.WriteFile(file, []byte("package ab\n\nfunc F() {}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	r = newTestReport(&gocov.Package{Name: "ab", Functions: []*gocov.Function{newFunction("F", file, 1)}})
+	buf.Reset()
+	if err := writeCSVReport(&buf, r); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), `"`+file+`"`) {
+		t.Errorf("file name is not quoted: %s", buf.String())
+	}
+	rows, err = csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 1 || rows[0][2] != file || rows[0][7] != "1" {
+		t.Errorf("unexpected rows: %q", rows)
+	}
+}
+
+func TestReportGroupDepth(t *testing.T) {
+	r := newTestReport(
+		&gocov.Package{Name: "example.com/a/x", Functions: []*gocov.Function{newFunction("F", "x.go", 1, 0)}},
+		&gocov.Package{Name: "example.com/a/y", Functions: []*gocov.Function{newFunction("F", "y.go", 1, 1)}},
+		&gocov.Package{Name: "example.com/b", Functions: []*gocov.Function{newFunction("F", "b.go", 0, 0)}},
+		&gocov.Package{Name: "other.org/c", Functions: []*gocov.Function{newFunction("F", "c.go", 1)}},
+	)
+	for _, test := range []struct {
+		depth    int
+		expected []reportOwner
+	}{{
+		depth: 1,
+		expected: []reportOwner{
+			{name: "example.com", statements: 6, statementsReached: 3},
+			{name: "other.org", statements: 1, statementsReached: 1},
+		},
+	}, {
+		depth: 2,
+		expected: []reportOwner{
+			{name: "example.com/a", statements: 4, statementsReached: 3},
+			{name: "example.com/b", statements: 2, statementsReached: 0},
+			{name: "other.org/c", statements: 1, statementsReached: 1},
+		},
+	}, {
+		// At the full depth, each package is its own group.
+		depth: 3,
+		expected: []reportOwner{
+			{name: "example.com/a/x", statements: 2, statementsReached: 1},
+			{name: "example.com/a/y", statements: 2, statementsReached: 2},
+			{name: "example.com/b", statements: 2, statementsReached: 0},
+			{name: "other.org/c", statements: 1, statementsReached: 1},
+		},
+	}} {
+		r.groupDepth = test.depth
+		if groups := r.groupReports(); !reflect.DeepEqual(groups, test.expected) {
+			t.Errorf("depth %d: got %+v, expected %+v", test.depth, groups, test.expected)
+		}
+	}
+
+	r.groupDepth = 2
+	var buf bytes.Buffer
+	printReport(&buf, r)
+	output := regexp.MustCompile("\t+").ReplaceAllString(buf.String(), "\t")
+	if line := "example.com/a\t 75.00% (3/4)\n"; !strings.Contains(output, line) || strings.Contains(output, "example.com/a/x") {
+		t.Errorf("expected %q in place of the packages in output:\n%s", line, output)
+	}
+	if report := newJSONReport(r); len(report.Groups) != 3 {
+		t.Errorf("unexpected groups in JSON report: %+v", report.Groups)
+	}
+}
+
+func TestReportByInterface(t *testing.T) {
+	// Only the Area methods are reached. Circle's Perimeter is not a
+	// method of Shape, so is not counted against Circle.
+	const pkg = "github.com/axw/gocov/gocov/testdata/iface"
+	r := newTestReport(testPackages(t, "./testdata/iface")...)
+	r.by = "interface"
+	var err error
+	r.implementations, err = findImplementations(pkg+".Shape", []string{pkg})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	printReport(&buf, r)
+	output := strings.Replace(buf.String(), "\t", "", -1)
+	for _, line := range []string{
+		pkg + ".Circle 25.00% (1/4)\n",
+		pkg + ".Square 50.00% (1/2)\n",
+	} {
+		if !strings.Contains(output, line) {
+			t.Errorf("expected %q in output:\n%s", line, output)
+		}
+	}
+	if report := newJSONReport(r); len(report.Implementations) != 2 {
+		t.Errorf("unexpected implementations in JSON report: %+v", report.Implementations)
+	}
+
+	// Interfaces of other packages are loaded from export data.
+	implementations, err := findImplementations("io.Reader", []string{pkg})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(implementations) != 1 || implementations[0].name != pkg+".upper" {
+		t.Errorf("unexpected implementations of io.Reader: %+v", implementations)
+	}
+	if _, err := findImplementations(pkg+".Square", []string{pkg}); err == nil {
+		t.Error("expected an error for a type that is not an interface")
+	}
+}
+
+func TestReportLineAttribution(t *testing.T) {
+	// The assignment to s spans lines 7-10, and the unreached return
+	// lines 12-14. With "all", the if statement containing the return
+	// is attributed only its first line, not the brace on line 15.
+	r := newTestReport(testPackages(t, "./testdata/multiline")...)
+	for _, test := range []struct {
+		attribution string
+		expected    map[int]bool
+	}{{
+		attribution: "all",
+		expected:    map[int]bool{7: true, 8: true, 9: true, 10: true, 11: true, 12: false, 13: false, 14: false, 16: true},
+	}, {
+		attribution: "first",
+		expected:    map[int]bool{7: true, 11: true, 12: false, 16: true},
+	}} {
+		r.lineAttribution = test.attribution
+		lines, err := r.coveredLines()
+		if err != nil {
+			t.Fatal(err)
+		}
+		for file, fileLines := range lines {
+			if filepath.Base(file) != "multiline.go" || !reflect.DeepEqual(fileLines, test.expected) {
+				t.Errorf("%s: got %s lines %v, expected %v", test.attribution, file, fileLines, test.expected)
+			}
+		}
+	}
+}
+
+func TestSonarQubeReport(t *testing.T) {
+	// In b.go, the if statement on line 5 is reached but the return
+	// that shares its line is not, so the line is not covered.
+	r := newTestReport(testPackages(t, "./testdata/sonarqube")...)
+	var buf bytes.Buffer
+	if err := writeSonarQubeReport(&buf, r); err != nil {
+		t.Fatal(err)
+	}
+	golden, err := ioutil.ReadFile("testdata/sonarqube/coverage.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != string(golden) {
+		t.Errorf("got:\n%s\nexpected:\n%s", buf.String(), golden)
+	}
+
+	// The report may be written to a file with -o.
+	dir, err := ioutil.TempDir("", "gocov")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	data, err := marshalJson(r.packages)
+	if err != nil {
+		t.Fatal(err)
+	}
+	input, output := filepath.Join(dir, "coverage.json"), filepath.Join(dir, "sonar-coverage.xml")
+	if err := ioutil.WriteFile(input, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	rc, stdout, stderr := runReport(t, "-format", "sonarqube", "-o", output, input)
+	if rc != 0 || stdout != "" {
+		t.Fatalf("report failed: %d\n%s%s", rc, stdout, stderr)
+	}
+	if data, err := ioutil.ReadFile(output); err != nil || string(data) != string(golden) {
+		t.Errorf("unexpected -o file: %v\n%s", err, data)
+	}
+}
+
+func TestShieldsReport(t *testing.T) {
+	colors, err := parseBadgeColors("50,80")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		reached []int64
+		message string
+		color   string
+	}{
+		{[]int64{0, 0, 0, 1}, "25.0%", "red"},
+		{[]int64{1, 1, 0, 0}, "50.0%", "yellow"},
+		{[]int64{1, 1, 1, 0}, "75.0%", "yellow"},
+		{[]int64{1, 1, 1, 1}, "100.0%", "green"},
+	}
+	for _, test := range tests {
+		r := newTestReport(&gocov.Package{
+			Name:      "example.com/a",
+			Functions: []*gocov.Function{newFunction("F", "a.go", test.reached...)},
+		})
+		r.badgeColors = colors
+		var buf bytes.Buffer
+		if err := writeShieldsReport(&buf, r); err != nil {
+			t.Fatal(err)
+		}
+		var badge shieldsBadge
+		if err := json.Unmarshal(buf.Bytes(), &badge); err != nil {
+			t.Fatal(err)
+		}
+		expect := shieldsBadge{1, "coverage", test.message, test.color}
+		if badge != expect {
+			t.Errorf("got %+v, expected %+v", badge, expect)
+		}
+	}
+
+	for _, invalid := range []string{"50", "80,50", "a,b"} {
+		if _, err := parseBadgeColors(invalid); err == nil {
+			t.Errorf("expected an error for %q", invalid)
+		}
+	}
+}
+
+func TestPrintMissedSource(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gocov")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	const source = "package p\n\nfunc Abs(x int) int {\n\tif x < 0 {\n\t\treturn -x\n\t}\n\treturn x\n}\n"
+	filename := filepath.Join(dir, "p.go")
+	if err := ioutil.WriteFile(filename, []byte(source), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	statement := func(text string, reached int64) *gocov.Statement {
+		start := strings.Index(source, text)
+		return &gocov.Statement{Start: start, End: start + len(text), Reached: reached}
+	}
+	r := newTestReport(&gocov.Package{
+		Name: "example.com/p",
+		Functions: []*gocov.Function{{
+			Name: "Abs",
+			File: filename,
+			Statements: []*gocov.Statement{
+				statement("if x < 0 {\n\t\treturn -x\n\t}", 1),
+				statement("return -x", 0),
+				statement("return x\n", 1),
+			},
+		}},
+	})
+	var buf bytes.Buffer
+	if err := printMissedSource(&buf, r, 1); err != nil {
+		t.Fatal(err)
+	}
+	expect := "\n" + filename + ":5:3\n\t\treturn -x\n\t\t^^^^^^^^^\n"
+	if buf.String() != expect {
+		t.Errorf("got:\n%q\nexpected:\n%q", buf.String(), expect)
+	}
+
+	buf.Reset()
+	if err := printMissedSource(&buf, r, 0); err != nil || buf.Len() != 0 {
+		t.Errorf("expected no output with n=0, got %q, %v", buf.String(), err)
+	}
+}
+
+func TestGitHubActionsReport(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gocov")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	const source = "package p\n\nfunc Sign(x int) int {\n\tif x < 0 {\n\t\treturn -1\n\t}\n\treturn 1\n}\n"
+	if err := os.Mkdir(filepath.Join(dir, "p"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	filename := filepath.Join(dir, "p", "p,1.go")
+	if err := ioutil.WriteFile(filename, []byte(source), 0644); err != nil {
+		t.Fatal(err)
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	statement := func(text string) *gocov.Statement {
+		start := strings.Index(source, text)
+		return &gocov.Statement{Start: start, End: start + len(text)}
+	}
+	r := newTestReport(&gocov.Package{
+		Name: "example.com/p",
+		Functions: []*gocov.Function{{
+			Name:       "Sign",
+			File:       filename,
+			Statements: []*gocov.Statement{statement("return -1"), statement("return 1")},
+		}},
+	})
+
+	var buf bytes.Buffer
+	if err := writeGitHubActionsReport(&buf, r); err != nil {
+		t.Fatal(err)
+	}
+	expect := "::warning file=p/p%2C1.go,line=5,col=3,endLine=5,endColumn=12::Statement not covered by tests\n" +
+		"::warning file=p/p%2C1.go,line=7,col=2,endLine=7,endColumn=10::Statement not covered by tests\n"
+	if buf.String() != expect {
+		t.Errorf("got:\n%s\nexpected:\n%s", buf.String(), expect)
+	}
+
+	buf.Reset()
+	r.maxAnnotations = 1
+	if err := writeGitHubActionsReport(&buf, r); err != nil {
+		t.Fatal(err)
+	}
+	expect = "::warning file=p/p%2C1.go,line=5,col=3,endLine=5,endColumn=12::Statement not covered by tests\n" +
+		"::notice::1 more statements not covered by tests\n"
+	if buf.String() != expect {
+		t.Errorf("got:\n%s\nexpected:\n%s", buf.String(), expect)
+	}
+}
+
+func TestTreemapReport(t *testing.T) {
+	r := newTestReport(
+		&gocov.Package{
+			Name: "example.com/a",
+			Functions: []*gocov.Function{
+				newFunction("F", "/src/a/f.go", 1, 0),
+				newFunction("G", "/src/a/g.go", 1, 1, 1),
+				newFunction("H", "/src/a/f.go", 0),
+			},
+		},
+		&gocov.Package{
+			Name:      "example.com/b",
+			Functions: []*gocov.Function{newFunction("T.M", "/src/b/b.go", 0, 1)},
+		},
+	)
+	// With -rel, the root is named by the module, and the packages
+	// still by their full import paths.
+	r.modulePath = "example.com"
+	var buf bytes.Buffer
+	if err := writeTreemapReport(&buf, r); err != nil {
+		t.Fatal(err)
+	}
+	var root treemapNode
+	if err := json.Unmarshal(buf.Bytes(), &root); err != nil {
+		t.Fatal(err)
+	}
+	leaf := func(name string, value, covered int) *treemapNode {
+		return &treemapNode{Name: name, Value: value, Covered: covered}
+	}
+	node := func(name string, value, covered int, children ...*treemapNode) *treemapNode {
+		return &treemapNode{name, value, covered, children}
+	}
+	expect := node("example.com", 8, 5,
+		node("example.com/a", 6, 4,
+			node("f.go", 3, 1, leaf("F", 2, 1), leaf("H", 1, 0)),
+			node("g.go", 3, 3, leaf("G", 3, 3)),
+		),
+		node("example.com/b", 2, 1,
+			node("b.go", 2, 1, leaf("T.M", 2, 1)),
+		),
+	)
+	if !reflect.DeepEqual(&root, expect) {
+		got, _ := json.MarshalIndent(&root, "", "  ")
+		t.Errorf("unexpected treemap:\n%s", got)
+	}
+}
+
+func TestUpdateLastCoverageConcurrent(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gocov")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, lastCoverageFile)
+
+	// Reports run at once, such as by parallel CI jobs in one
+	// working directory, never see a partly written file.
+	var wg sync.WaitGroup
+	errs := make(chan error, 8)
+	for i := 0; i < 8; i++ {
+		r := newTestReport(&gocov.Package{
+			Name:      fmt.Sprintf("example.com/p%d", i),
+			Functions: []*gocov.Function{newFunction("F", "p.go", 1, 0)},
+		})
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				if _, err := r.updateLastCoverage(filename); err != nil {
+					errs <- err
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+	files, err := filepath.Glob(filepath.Join(dir, "*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 {
+		t.Errorf("expected only %s, got %q", lastCoverageFile, files)
+	}
+}
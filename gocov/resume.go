@@ -0,0 +1,162 @@
+// Copyright (c) 2026 The Gocov Authors.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// resumeStateFile is the file within the -resume directory listing
+// the package runs that completed.
+const resumeStateFile = "completed"
+
+// resumeState records the package runs of gocov test -resume whose
+// tests passed, and whose profiles are kept in the directory.
+type resumeState struct {
+	dir       string
+	mu        sync.Mutex
+	completed map[string]bool
+}
+
+// readResumeState reads the runs completed by previous runs of gocov
+// test with the same -resume directory, creating it if necessary. The
+// state file is created at once, so that the directory is known to be
+// gocov's even before any run completes. An existing directory without
+// it is refused unless it is empty, as its files are not gocov's.
+func readResumeState(dir string) (*resumeState, error) {
+	s := &resumeState{dir: dir, completed: make(map[string]bool)}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	stateFile := filepath.Join(dir, resumeStateFile)
+	if _, err := os.Stat(stateFile); os.IsNotExist(err) {
+		infos, err := ioutil.ReadDir(dir)
+		if err != nil {
+			return nil, err
+		}
+		if len(infos) > 0 {
+			return nil, fmt.Errorf("-resume directory %s is not empty and was not written by gocov test -resume", dir)
+		}
+		if err := ioutil.WriteFile(stateFile, nil, 0644); err != nil {
+			return nil, err
+		}
+	} else if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(stateFile)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		s.completed[scanner.Text()] = true
+	}
+	return s, scanner.Err()
+}
+
+// resumeKey identifies a run by its profile, package and arguments, so
+// that a run is only skipped if it would be repeated exactly.
+func resumeKey(run packageRun) string {
+	return fmt.Sprintf("%s\t%s\t%s", filepath.Base(run.coverFile), run.pkg, strings.Join(run.args, " "))
+}
+
+// done reports whether the run was completed by a previous run.
+func (s *resumeState) done(run packageRun) bool {
+	if s == nil {
+		return false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.completed[resumeKey(run)]
+}
+
+// record records that the run completed, once its profile is written.
+func (s *resumeState) record(run packageRun) error {
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	f, err := os.OpenFile(filepath.Join(s.dir, resumeStateFile), os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(f, resumeKey(run))
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	s.completed[resumeKey(run)] = true
+	return err
+}
+
+// prune removes the profiles in the directory that do not belong to a
+// completed run of runs, such as those of an interrupted run or of a
+// previous run of other packages, so that they are not merged.
+func (s *resumeState) prune(runs []packageRun) error {
+	keep := make(map[string]bool)
+	for _, run := range runs {
+		if s.done(run) {
+			keep[filepath.Base(run.coverFile)] = true
+		}
+	}
+	files, err := filepath.Glob(filepath.Join(s.dir, "test*.cov"))
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		if !keep[filepath.Base(file)] {
+			if err := os.Remove(file); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// remove removes what gocov wrote to the directory once all of the
+// tests have passed: the state file, the profiles, and the test
+// binaries and coverage data of -exit-coverage. Anything else is left
+// alone, and the directory itself is removed only if nothing else is
+// in it.
+func (s *resumeState) remove() error {
+	infos, err := ioutil.ReadDir(s.dir)
+	if err != nil {
+		return err
+	}
+	for _, info := range infos {
+		if info.Name() != resumeStateFile && !isTestRunFile(info) {
+			continue
+		}
+		if err := os.RemoveAll(filepath.Join(s.dir, info.Name())); err != nil {
+			return err
+		}
+	}
+	// The directory is kept if anything else was put in it.
+	os.Remove(s.dir)
+	return nil
+}
@@ -0,0 +1,108 @@
+// Copyright (c) 2026 The Gocov Authors.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package main
+
+import (
+	"errors"
+	"io/ioutil"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+)
+
+// splitProgramArgs splits the arguments to "gocov run" into those
+// for gocov, and those following "--" for the program.
+func splitProgramArgs(args []string) (gocovArgs, programArgs []string) {
+	for i, arg := range args {
+		if arg == "--" {
+			return args[:i], args[i+1:]
+		}
+	}
+	return args, nil
+}
+
+// runProgram builds a main package with coverage enabled, runs it,
+// and then outputs the coverage of the run. The arguments are any
+// build flags followed by the package, and optionally "--" and the
+// arguments to pass to the program.
+func runProgram(args []string) error {
+	if err := checkGoTool(); err != nil {
+		return err
+	}
+	args, programArgs := splitProgramArgs(args)
+	if len(args) == 0 {
+		return errors.New("missing package")
+	}
+	pkg, buildFlags := args[len(args)-1], args[:len(args)-1]
+
+	tmpDir, err := ioutil.TempDir("", "gocov")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		err := os.RemoveAll(tmpDir)
+		if err != nil {
+			log.Printf("failed to clean up temp directory %q", tmpDir)
+		}
+	}()
+
+	binary := filepath.Join(tmpDir, "program")
+	if runtime.GOOS == "windows" {
+		binary += ".exe"
+	}
+	cmdArgs := append([]string{"build", "-cover", "-o", binary}, buildFlags...)
+	cmdArgs = append(cmdArgs, pkg)
+	cmd := exec.Command("go", cmdArgs...)
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return err
+	}
+
+	coverDir := filepath.Join(tmpDir, "cover")
+	if err := os.Mkdir(coverDir, 0755); err != nil {
+		return err
+	}
+	cmd = exec.Command(binary, programArgs...)
+	cmd.Env = append(os.Environ(), "GOCOVERDIR="+coverDir)
+	cmd.Stdin = os.Stdin
+	// Write all program output to stderr so as not to interfere with
+	// the JSON coverage output.
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+	runErr := cmd.Run()
+
+	// Coverage is written even if the program exits with an error,
+	// so convert it before reporting the program's failure.
+	coverFile := filepath.Join(tmpDir, "program.cov")
+	cmd = exec.Command("go", "tool", "covdata", "textfmt", "-i", coverDir, "-o", coverFile)
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return err
+	}
+	if err := convertProfiles(coverFile); err != nil {
+		return err
+	}
+	return runErr
+}
@@ -0,0 +1,51 @@
+// Copyright (c) 2026 The Gocov Authors.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRunProgram(t *testing.T) {
+	var err error
+	stdout, stderr := captureOutput(t, func() {
+		err = runProgram([]string{
+			"-tags", "example", "./testdata/echo",
+			"--", "serve", "--port", "8080", "a b", "--",
+		})
+	})
+	if err != nil {
+		t.Fatalf("%v\n%s", err, stderr)
+	}
+	expect := `"serve"` + "\n" + `"--port"` + "\n" + `"8080"` + "\n" + `"a b"` + "\n" + `"--"` + "\n"
+	if !strings.Contains(stderr, expect) {
+		t.Errorf("expected arguments %q in program output:\n%s", expect, stderr)
+	}
+	packages, err := unmarshalJson([]byte(stdout))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reached := statementsReached(packages); !reflect.DeepEqual(reached, map[string]int{"main": 2}) {
+		t.Errorf("unexpected coverage: %v", reached)
+	}
+}
@@ -0,0 +1,68 @@
+// Copyright (c) 2026 The Gocov Authors.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package main
+
+import (
+	"go/token"
+	"io/ioutil"
+)
+
+// sourceFiles reads source files, recording their lines in a
+// token.FileSet for mapping offsets to line and column numbers.
+type sourceFiles struct {
+	fset  *token.FileSet
+	files map[string]*token.File
+}
+
+func newSourceFiles() *sourceFiles {
+	return &sourceFiles{
+		fset:  token.NewFileSet(),
+		files: make(map[string]*token.File),
+	}
+}
+
+// file returns the named source file, reading it if it has not
+// already been read.
+func (s *sourceFiles) file(name string) (*token.File, error) {
+	if f, ok := s.files[name]; ok {
+		return f, nil
+	}
+	data, err := ioutil.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	f := s.fset.AddFile(name, s.fset.Base(), len(data))
+	f.SetLinesForContent(data)
+	s.files[name] = f
+	return f, nil
+}
+
+// offsetPosition returns the 1-based line and column numbers of
+// offset in file, with columns counted in bytes. Offsets beyond the
+// end of the file, which has changed since coverage was recorded,
+// are treated as the end of the file.
+func offsetPosition(file *token.File, offset int) (line, col int) {
+	if offset > file.Size() {
+		offset = file.Size()
+	}
+	pos := file.Position(file.Pos(offset))
+	return pos.Line, pos.Column
+}
@@ -22,75 +22,638 @@ package main
 
 import (
 	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"log"
+	"net/url"
 	"os"
 	"os/exec"
+	"path"
 	"path/filepath"
+	"runtime"
+	"strconv"
 	"strings"
+	"sync"
 
+	"github.com/axw/gocov"
 	"github.com/axw/gocov/gocov/internal/testflag"
+	"github.com/axw/gocov/gocov/internal/testjson"
+	"github.com/axw/gocov/gocovutil"
 )
 
-// resolvePackages returns a slice of resolved package names, given a slice of
-// package names that could be relative or recursive.
+var (
+	testFlags     = flag.NewFlagSet("test", flag.ExitOnError)
+	testQuietFlag = testFlags.Bool(
+		"quiet", false,
+		"Suppress go test output unless the tests fail")
+	testPerTestFlag = testFlags.Bool(
+		"per-test", false,
+		"Run each test individually, and output the coverage of each")
+	testNoTestFlag = testFlags.Bool(
+		"no-test", false,
+		"Build test binaries with coverage enabled, but do not run them")
+	testOutdirFlag = testFlags.String(
+		"outdir", ".",
+		"Directory in which to write test binaries built with -no-test")
+	testMaxTestOutputFlag = testFlags.Int(
+		"max-test-output", 0,
+		"Maximum number of bytes of each package's go test output to buffer with -quiet or -parallel-packages, "+
+			"keeping its beginning and end, or 0 for no limit")
+	testResumeFlag = testFlags.String(
+		"resume", "",
+		"Keep the profiles of packages whose tests pass in this directory, so that a rerun after an interruption tests only the rest; "+
+			"removed once all tests pass")
+	testFileFlag = testFlags.String(
+		"file", "",
+		"Output only the coverage of the statements in this file; a relative path matches any file with the same trailing path")
+	testExitCoverageFlag = testFlags.Bool(
+		"exit-coverage", false,
+		"Build each package's test binary and run it directly, so that its coverage is kept even if a test calls os.Exit")
+	testRaceCompareFlag = testFlags.Bool(
+		"race-compare", false,
+		`Run the tests both without and with -race, outputting the coverage of each, labeled "normal" and "race", along with their union`)
+	testFailFastFlag = testFlags.Bool(
+		"fail-fast", false,
+		"Stop testing packages once the tests of one fail, stopping any being tested in parallel, and output the coverage so far")
+	testTimeoutPerPackageFlag = testFlags.Duration(
+		"timeout-per-package", 0,
+		"Stop the tests of a package if they run for longer than this; other packages are still tested")
+	testPackagesFileFlag = testFlags.String(
+		"packages-file", "",
+		"Read the packages to test, one per line, from this file, or from stdin if \"-\"")
+	testTimingsFlag = testFlags.Bool(
+		"timings", false,
+		"Print the time spent resolving packages, testing each package, and converting coverage")
+	testArchiveFlag = testFlags.String(
+		"archive", "",
+		"Also write the coverage, test output and details of the run to this .tar.gz file")
+	testParallelPackagesFlag = testFlags.Int(
+		"parallel-packages", 1,
+		"Maximum number of packages whose tests are run at once")
+	testOutputFlag = testFlags.String(
+		"o", "-",
+		"File to which to write the coverage, or \"-\" for stdout; go test output is always written to stderr")
+	testAppendFlag = testFlags.Bool(
+		"append", false,
+		"Append the coverage to the -o file as a line of JSON rather than replacing it, so that several runs may share the file")
+	testSplitOutputFlag = testFlags.String(
+		"split-output", "",
+		"Write the coverage of each package to its own file in this directory, rather than to -o")
+	testPostHookFlag = testFlags.String(
+		"post-hook", "",
+		"Shell command to run after the coverage is written, given its path as $1 and in GOCOV_OUTPUT")
+	testPostHookRequiredFlag = testFlags.Bool(
+		"post-hook-required", false,
+		"Fail if the -post-hook command fails, rather than only warning")
+	testTmpNameFlag = testFlags.String(
+		"tmpname", "",
+		"Name the temp directory holding coverage profiles gocov-<name>, rather than a random name, so that its path is the same in each run's logs")
+	testIncludeVendorFlag = testFlags.Bool(
+		"include-vendor", false,
+		"Test packages within vendor directories, which are otherwise skipped")
+	testEnvFlag  envFlag
+	testTagsFlag tagsFlag
+	testCgoFlag  cgoFlag
+)
+
+func init() {
+	testFlags.Var(&testEnvFlag, "env",
+		"Set an environment variable, in the form KEY=VALUE, when running tests; may be repeated")
+	testFlags.Var(&testTagsFlag, "tags",
+		"Build tags with which to run tests; if repeated, tests are run once per set of tags, and the coverage combined")
+	testFlags.Var(&testCgoFlag, "cgo",
+		"Run tests with CGO_ENABLED set to 1 if true or 0 if false; if not given, CGO_ENABLED is inherited")
+}
+
+// envFlag is a repeatable flag holding KEY=VALUE pairs.
+type envFlag struct {
+	repeatedFlag
+}
+
+func (f *envFlag) Set(value string) error {
+	if strings.Index(value, "=") <= 0 {
+		return fmt.Errorf("expected KEY=VALUE")
+	}
+	f.add(value)
+	return nil
+}
+
+// tagsFlag is a repeatable flag holding sets of build tags.
+type tagsFlag struct {
+	repeatedFlag
+}
+
+func (f *tagsFlag) Set(value string) error {
+	f.add(value)
+	return nil
+}
+
+// cgoFlag is a boolean flag that records whether it was given,
+// so that CGO_ENABLED is only overridden when asked.
+type cgoFlag struct {
+	set     bool
+	enabled bool
+}
+
+func (f *cgoFlag) IsBoolFlag() bool { return true }
+
+func (f *cgoFlag) String() string {
+	if !f.set {
+		return ""
+	}
+	return strconv.FormatBool(f.enabled)
+}
+
+func (f *cgoFlag) Set(value string) error {
+	enabled, err := strconv.ParseBool(value)
+	if err != nil {
+		return err
+	}
+	f.set, f.enabled = true, enabled
+	return nil
+}
+
+// setenv returns env with the variable key set to value, replacing
+// any existing value.
+func setenv(env []string, key, value string) []string {
+	result := make([]string, 0, len(env)+1)
+	for _, kv := range env {
+		if !strings.HasPrefix(kv, key+"=") {
+			result = append(result, kv)
+		}
+	}
+	return append(result, key+"="+value)
+}
+
+// testEnviron returns the environment in which to run tests: the
+// environment of gocov, overridden by any -env and -cgo flags.
+func testEnviron() []string {
+	env := os.Environ()
+	for _, kv := range testEnvFlag.values {
+		equals := strings.Index(kv, "=")
+		env = setenv(env, kv[:equals], kv[equals+1:])
+	}
+	if testCgoFlag.set {
+		value := "0"
+		if testCgoFlag.enabled {
+			value = "1"
+		}
+		env = setenv(env, "CGO_ENABLED", value)
+	}
+	return env
+}
+
+// boolFlag is implemented by flag values that do not require
+// an argument.
+type boolFlag interface {
+	IsBoolFlag() bool
+}
+
+// isArgsSeparator reports whether arg ends the flags given to gocov
+// and go test: "--", or go test's "-args", which passes the rest of
+// the command line to the test binary unchanged.
+func isArgsSeparator(arg string) bool {
+	return arg == "--" || arg == "-args" || arg == "--args"
+}
+
+// withPackage returns the arguments of go test for testing pkg with
+// the flags in args: pkg is placed before any "--" or "-args", as the
+// go command passes the arguments following them to the test binary.
+func withPackage(args []string, pkg string) []string {
+	for i, arg := range args {
+		if isArgsSeparator(arg) {
+			return append(append(append([]string(nil), args[:i]...), pkg), args[i:]...)
+		}
+	}
+	return append(append([]string(nil), args...), pkg)
+}
+
+// extractFlags parses the flags in args that are defined in fs,
+// and returns the remaining arguments in their original order.
+// Arguments from "--" or "-args" on are never parsed.
+func extractFlags(fs *flag.FlagSet, args []string) ([]string, error) {
+	var rest []string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if isArgsSeparator(arg) {
+			rest = append(rest, args[i:]...)
+			break
+		}
+		if !strings.HasPrefix(arg, "-") {
+			rest = append(rest, arg)
+			continue
+		}
+		name := strings.TrimPrefix(arg[1:], "-")
+		value, hasValue := "", false
+		if equals := strings.Index(name, "="); equals >= 0 {
+			name, value, hasValue = name[:equals], name[equals+1:], true
+		}
+		f := fs.Lookup(name)
+		if f == nil {
+			rest = append(rest, arg)
+			continue
+		}
+		if !hasValue {
+			if b, ok := f.Value.(boolFlag); ok && b.IsBoolFlag() {
+				value = "true"
+			} else if i+1 < len(args) {
+				i++
+				value = args[i]
+			} else {
+				return nil, fmt.Errorf("flag needs an argument: -%s", name)
+			}
+		}
+		if err := fs.Set(name, value); err != nil {
+			return nil, fmt.Errorf("invalid value %q for flag -%s: %v", value, name, err)
+		}
+	}
+	return rest, nil
+}
+
+// removeVerboseFlag returns args without any -v flags. Arguments
+// from "--" or "-args" on are left as they are.
+func removeVerboseFlag(args []string) []string {
+	var result []string
+	for i, arg := range args {
+		if isArgsSeparator(arg) {
+			return append(result, args[i:]...)
+		}
+		name := arg
+		if strings.HasPrefix(name, "--") { // reduce two minuses to one
+			name = name[1:]
+		}
+		switch name {
+		case "-v", "-v=true", "-test.v", "-test.v=true":
+			continue
+		}
+		result = append(result, arg)
+	}
+	return result
+}
+
+// resolvePackages returns a slice of unique resolved package names, given a
+// slice of package names that could be relative or recursive. Vendored
+// packages are excluded, unless -include-vendor is specified.
 func resolvePackages(pkgs []string) ([]string, error) {
-	var buf bytes.Buffer
-	cmd := exec.Command("go", append([]string{"list", "-e"}, pkgs...)...)
-	cmd.Stdin = os.Stdin
-	cmd.Stdout = &buf
-	cmd.Stderr = os.Stderr
-	err := cmd.Run()
+	pkgs, err := expandPackageGlobs(pkgs)
+	if err != nil {
+		return nil, err
+	}
+	output, err := goList(listFormat, pkgs, os.Stderr)
 	if err != nil {
 		return nil, err
 	}
+	// Packages may be named more than once, directly or via
+	// aliases; each is only tested once.
 	var resolvedPkgs []string
-	lines := strings.Split(buf.String(), "\n")
+	seen := make(map[string]bool)
+	lines := strings.Split(string(output), "\n")
 	for _, line := range lines {
 		line = strings.TrimSpace(line)
-		if len(line) > 0 {
-			resolvedPkgs = append(resolvedPkgs, line)
+		if len(line) == 0 {
+			continue
+		}
+		var dir, root string
+		if fields := strings.Split(line, "\t"); len(fields) == 3 {
+			line, dir, root = fields[0], fields[1], fields[2]
 		}
+		if seen[line] {
+			continue
+		}
+		seen[line] = true
+		if !*testIncludeVendorFlag && (isVendored(line) || isVendoredDir(dir, root)) {
+			fmt.Fprintf(os.Stderr, "skipping vendored package %s (use -include-vendor to test it)\n", line)
+			continue
+		}
+		resolvedPkgs = append(resolvedPkgs, line)
 	}
 	return resolvedPkgs, nil
 }
 
+// goList runs "go list -e" with the format and any further flags for
+// the packages, and returns its output. Long lists of packages are
+// listed in batches, to stay within the limits on the length of
+// command lines.
+func goList(format string, pkgs []string, stderr io.Writer, flags ...string) ([]byte, error) {
+	var buf bytes.Buffer
+	for {
+		batch := pkgs
+		if len(batch) > maxListPackages {
+			batch = batch[:maxListPackages]
+		}
+		pkgs = pkgs[len(batch):]
+		cmdArgs := append([]string{"list", "-e", "-f", format}, flags...)
+		cmdArgs = append(cmdArgs, batch...)
+		cmd := exec.Command("go", cmdArgs...)
+		cmd.Stdin = os.Stdin
+		cmd.Stdout = &buf
+		cmd.Stderr = stderr
+		if err := cmd.Run(); err != nil {
+			return nil, err
+		}
+		if len(pkgs) == 0 {
+			return buf.Bytes(), nil
+		}
+	}
+}
+
+// expandPackageGlobs replaces the glob patterns among pkgs, such as
+// "internal/**/service", with the directories of the packages within
+// the working directory that match them, relative to it. "*" matches
+// within a path element, and "**" any number of elements. Arguments
+// without glob metacharacters, which import paths and the patterns of
+// the go command never contain, are left alone. It is an error for a
+// pattern to match no package.
+func expandPackageGlobs(pkgs []string) ([]string, error) {
+	var dirs []string
+	var expanded []string
+	for _, pkg := range pkgs {
+		if !strings.ContainsAny(pkg, "*?[") {
+			expanded = append(expanded, pkg)
+			continue
+		}
+		if dirs == nil {
+			output, err := goList("{{.Dir}}", []string{"./..."}, os.Stderr)
+			if err != nil {
+				return nil, err
+			}
+			wd, err := os.Getwd()
+			if err != nil {
+				return nil, err
+			}
+			dirs = []string{}
+			for _, dir := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+				if rel, err := filepath.Rel(wd, dir); err == nil && dir != "" {
+					dirs = append(dirs, filepath.ToSlash(rel))
+				}
+			}
+		}
+		pattern := strings.Split(strings.TrimPrefix(filepath.ToSlash(pkg), "./"), "/")
+		var matched bool
+		for _, dir := range dirs {
+			if matchElems(pattern, strings.Split(dir, "/")) {
+				expanded = append(expanded, "./"+dir)
+				matched = true
+			}
+		}
+		if !matched {
+			return nil, fmt.Errorf("no packages match %s", pkg)
+		}
+	}
+	return expanded, nil
+}
+
+// listFormat is the format with which resolvePackages lists each
+// package: its import path, its directory, and the root directory of
+// its module, or of its GOPATH entry outside of module mode.
+const listFormat = "{{.ImportPath}}\t{{.Dir}}\t{{if .Module}}{{.Module.Dir}}{{else}}{{.Root}}{{end}}"
+
+// maxListPackages is the maximum number of packages passed to
+// each invocation of "go list" by goList.
+const maxListPackages = 1000
+
+// readPackagesFile returns the packages listed in the named file, one
+// per line, ignoring blank lines. The file "-" is read from stdin.
+func readPackagesFile(filename string) ([]string, error) {
+	var data []byte
+	var err error
+	if filename == "-" {
+		data, err = ioutil.ReadAll(os.Stdin)
+	} else {
+		data, err = ioutil.ReadFile(filename)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var pkgs []string
+	for _, line := range strings.Split(string(data), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			pkgs = append(pkgs, line)
+		}
+	}
+	if len(pkgs) == 0 {
+		return nil, fmt.Errorf("no packages listed in %s", filename)
+	}
+	return pkgs, nil
+}
+
+// isVendored reports whether the slash-separated path contains a
+// "vendor" element.
+func isVendored(path string) bool {
+	for _, elem := range strings.Split(path, "/") {
+		if elem == "vendor" {
+			return true
+		}
+	}
+	return false
+}
+
+// isVendoredDir reports whether dir is within a vendor directory
+// below root. Directories named "vendor" above root, such as in the
+// path of the checkout, are not considered.
+func isVendoredDir(dir, root string) bool {
+	if dir == "" || root == "" {
+		return false
+	}
+	rel, err := filepath.Rel(root, dir)
+	if err != nil {
+		return false
+	}
+	return isVendored(filepath.ToSlash(rel))
+}
+
+// checkGoTool verifies that the go tool can be found in PATH, as
+// it is required to resolve packages and run tests.
+func checkGoTool() error {
+	if _, err := exec.LookPath("go"); err != nil {
+		return environmentError{fmt.Errorf("the go tool could not be found in PATH; " +
+			"please install Go or add its bin directory to PATH")}
+	}
+	return nil
+}
+
 func runTests(args []string) error {
-	pkgs, testFlags := testflag.Split(args)
-	pkgs, err := resolvePackages(pkgs)
+	if err := checkGoTool(); err != nil {
+		return err
+	}
+	if err := applyConfig(testFlags); err != nil {
+		return err
+	}
+	args, err := extractFlags(testFlags, args)
+	if err != nil {
+		return err
+	}
+	pkgs, passToTest := testflag.Split(args)
+	if _, ok := flagValue(passToTest, "fuzz"); ok {
+		// go test refuses -coverprofile with -fuzz. Without
+		// -fuzz, fuzz targets still run their seed corpus.
+		return fmt.Errorf("go test does not support -fuzz with coverage profiles; " +
+			"fuzz separately, and add any interesting inputs to the seed corpus")
+	}
+	if *testQuietFlag {
+		passToTest = removeVerboseFlag(passToTest)
+	}
+	if *testRaceCompareFlag {
+		for _, arg := range passToTest {
+			if name := strings.TrimLeft(arg, "-"); name == "race" || strings.HasPrefix(name, "race=") {
+				return fmt.Errorf("-race-compare runs the tests both without and with -race, so -race cannot also be given")
+			}
+		}
+	}
+	if *testPackagesFileFlag != "" {
+		listed, err := readPackagesFile(*testPackagesFileFlag)
+		if err != nil {
+			return err
+		}
+		pkgs = append(pkgs, listed...)
+	}
+	var timer *timings
+	if *testTimingsFlag {
+		timer = &timings{}
+		defer timer.write(os.Stderr)
+	}
+	err = timer.time("resolve", "", func() error {
+		var err error
+		pkgs, err = resolvePackages(pkgs)
+		return err
+	})
 	if err != nil {
 		return err
 	}
 
-	tmpDir, err := ioutil.TempDir("", "gocov")
+	if *testNoTestFlag && *testPerTestFlag {
+		return fmt.Errorf("-no-test and -per-test cannot be used together")
+	}
+	if *testArchiveFlag != "" && (*testNoTestFlag || *testPerTestFlag) {
+		return fmt.Errorf("-archive cannot be used with -no-test or -per-test")
+	}
+	if len(testTagsFlag.values) > 1 && (*testNoTestFlag || *testPerTestFlag) {
+		return fmt.Errorf("-tags may only be repeated when running all tests together")
+	}
+	if *testSplitOutputFlag != "" && (*testOutputFlag != "-" || *testAppendFlag) {
+		return fmt.Errorf("-split-output cannot be used with -o or -append")
+	}
+	if *testSplitOutputFlag != "" && (*testNoTestFlag || *testPerTestFlag) {
+		return fmt.Errorf("-split-output cannot be used with -no-test or -per-test")
+	}
+	if *testPostHookFlag != "" && (*testNoTestFlag || *testPerTestFlag) {
+		return fmt.Errorf("-post-hook cannot be used with -no-test or -per-test")
+	}
+	if *testRaceCompareFlag && (*testNoTestFlag || *testPerTestFlag || *testSplitOutputFlag != "") {
+		return fmt.Errorf("-race-compare cannot be used with -no-test, -per-test or -split-output")
+	}
+	if *testResumeFlag != "" && (*testNoTestFlag || *testPerTestFlag || *testTmpNameFlag != "") {
+		return fmt.Errorf("-resume cannot be used with -no-test, -per-test or -tmpname")
+	}
+	if *testFileFlag != "" && (*testNoTestFlag || *testPerTestFlag) {
+		return fmt.Errorf("-file cannot be used with -no-test or -per-test")
+	}
+	if *testExitCoverageFlag && (*testNoTestFlag || *testPerTestFlag) {
+		return fmt.Errorf("-exit-coverage cannot be used with -no-test or -per-test")
+	}
+	if *testAppendFlag && *testOutputFlag == "-" {
+		return fmt.Errorf("-append requires -o to name a file")
+	}
+	if testCgoFlag.set {
+		for _, kv := range testEnvFlag.values {
+			if strings.HasPrefix(kv, "CGO_ENABLED=") {
+				return fmt.Errorf("-cgo cannot be used with -env CGO_ENABLED")
+			}
+		}
+	}
+	if len(testTagsFlag.values) == 1 {
+		passToTest = append([]string{"-tags=" + testTagsFlag.values[0]}, passToTest...)
+	}
+	if *testNoTestFlag {
+		return buildTests(pkgs, passToTest)
+	}
+	if *testArchiveFlag != "" {
+		testLog = &bytes.Buffer{}
+		defer func() { testLog = nil }()
+	}
+
+	// With -resume, the profiles are kept in its directory until all
+	// of the tests pass.
+	var resume *resumeState
+	var resumeDone bool
+	var tmpDir string
+	if *testResumeFlag != "" {
+		resume, err = readResumeState(*testResumeFlag)
+		tmpDir = *testResumeFlag
+	} else {
+		tmpDir, err = makeTempDir(os.TempDir(), *testTmpNameFlag)
+	}
 	if err != nil {
 		return err
 	}
 	defer func() {
+		if resume != nil {
+			if !resumeDone {
+				return
+			}
+			if err := resume.remove(); err != nil {
+				log.Printf("failed to clean up -resume directory %q: %v", tmpDir, err)
+			}
+			return
+		}
 		err := os.RemoveAll(tmpDir)
 		if err != nil {
 			log.Printf("failed to clean up temp directory %q", tmpDir)
 		}
 	}()
 
+	if *testPerTestFlag {
+		return runPerTest(tmpDir, pkgs, passToTest)
+	}
+
+	// Each set of tags selects different files, so the tests are run
+	// once for each; the profiles are combined by convertProfiles.
+	variants := [][]string{passToTest}
+	if len(testTagsFlag.values) > 1 {
+		variants = nil
+		for _, tags := range testTagsFlag.values {
+			variants = append(variants, append([]string{"-tags=" + tags}, passToTest...))
+		}
+	}
+	// With -race-compare, each is also run with -race, and labeled so
+	// that the coverage of each label is output separately.
+	labels := []string{""}
+	if *testRaceCompareFlag {
+		labels = []string{"normal", "race"}
+	}
+
 	// Unique -coverprofile file names are used so that all the files can be
 	// later merged into a single file.
-	for i, pkg := range pkgs {
-		coverFile := filepath.Join(tmpDir, fmt.Sprintf("test%d.cov", i))
-		cmdArgs := append([]string{"test", "-coverprofile", coverFile}, testFlags...)
-		cmdArgs = append(cmdArgs, pkg)
-		cmd := exec.Command("go", cmdArgs...)
-		cmd.Stdin = nil
-		// Write all test command output to stderr so as not to interfere with
-		// the JSON coverage output.
-		cmd.Stdout = os.Stderr
-		cmd.Stderr = os.Stderr
-		err := cmd.Run()
-		if err != nil {
+	var runs []packageRun
+	for _, label := range labels {
+		for _, args := range variants {
+			if label == "race" {
+				args = append([]string{"-race"}, args...)
+			}
+			for _, pkg := range pkgs {
+				coverFile := filepath.Join(tmpDir, fmt.Sprintf("test%d.cov", len(runs)))
+				runs = append(runs, packageRun{pkg, coverFile, args, label})
+			}
+		}
+	}
+	if resume != nil {
+		if err := resume.prune(runs); err != nil {
 			return err
 		}
 	}
+	// If the tests fail, the archive is still written, with the
+	// coverage of the packages tested, before the failure is returned.
+	// With -fail-fast, the coverage of the packages tested is output
+	// before the failure is returned.
+	timedOut, testErr := testPackageRuns(runs, timer, resume)
+	if testErr != nil && *testArchiveFlag == "" && !*testFailFastFlag {
+		return testErr
+	}
 
 	// Packages without tests will not produce a coverprofile; only pick up the
 	// ones that were created.
@@ -100,5 +663,729 @@ func runTests(args []string) error {
 	}
 
 	// Merge the profiles.
-	return convertProfiles(files...)
+	var ps gocovutil.Packages
+	var coverage []byte
+	err = timer.time("convert", "", func() error {
+		var err error
+		ps, err = mergeProfiles(files...)
+		if err != nil {
+			return err
+		}
+		if *testFileFlag != "" {
+			if ps, err = filterFile(ps, *testFileFlag); err != nil {
+				return err
+			}
+		}
+		if !*testRaceCompareFlag {
+			coverage, err = marshalJson(ps)
+			return err
+		}
+		variants, err := labeledCoverage(runs, labels)
+		if err != nil {
+			return err
+		}
+		coverage, err = json.Marshal(struct {
+			Packages []*gocov.Package
+			Variants []coverageVariant
+		}{ps, variants})
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	var timeoutErr error
+	if len(timedOut) > 0 {
+		timeoutErr = fmt.Errorf("%d of %d packages timed out: %s",
+			len(timedOut), len(runs), strings.Join(timedOut, ", "))
+	}
+	if *testArchiveFlag != "" {
+		runErr := testErr
+		if runErr == nil {
+			runErr = timeoutErr
+		}
+		metadata := newArchiveMetadata(pkgs, runErr)
+		err := writeArchive(*testArchiveFlag, coverage, testLog.Bytes(), metadata)
+		if err != nil {
+			return err
+		}
+	}
+	if testErr != nil && !*testFailFastFlag {
+		return testErr
+	}
+	if *testSplitOutputFlag != "" {
+		err = writeSplitCoverage(*testSplitOutputFlag, ps)
+	} else {
+		err = writeCoverage(coverage)
+	}
+	if err != nil {
+		return err
+	}
+	if testErr != nil {
+		return testErr
+	}
+	resumeDone = timeoutErr == nil
+	if timeoutErr != nil || *testPostHookFlag == "" {
+		return timeoutErr
+	}
+	// The hook is given the path of the coverage; coverage written
+	// to stdout is also written to a file for it.
+	output := *testOutputFlag
+	if *testSplitOutputFlag != "" {
+		output = *testSplitOutputFlag
+	} else if output == "-" {
+		dir := tmpDir
+		if resume != nil {
+			// The -resume directory is the user's, so the file is
+			// written to a temporary directory of its own.
+			if dir, err = makeTempDir(os.TempDir(), ""); err != nil {
+				return err
+			}
+			defer os.RemoveAll(dir)
+		}
+		output = filepath.Join(dir, "coverage.json")
+		if err := ioutil.WriteFile(output, append(coverage, '\n'), 0644); err != nil {
+			return err
+		}
+	}
+	return runPostHook(*testPostHookFlag, output)
+}
+
+// runPostHook runs the -post-hook command with the shell, giving it the
+// path of the coverage output. Its output is written to stderr, so that
+// stdout holds only the coverage. Unless -post-hook-required is given,
+// failure of the command is only reported.
+func runPostHook(command, output string) error {
+	output, err := filepath.Abs(output)
+	if err != nil {
+		return err
+	}
+	cmd := shellCommand(command, output)
+	cmd.Env = setenv(os.Environ(), "GOCOV_OUTPUT", output)
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		if *testPostHookRequiredFlag {
+			return fmt.Errorf("-post-hook failed: %v", err)
+		}
+		fmt.Fprintf(os.Stderr, "warning: -post-hook failed: %v\n", err)
+	}
+	return nil
+}
+
+// writeSplitCoverage writes the coverage of each package to its own
+// file in dir, named by the package's import path escaped so that it
+// is a single path element.
+func writeSplitCoverage(dir string, packages []*gocov.Package) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	for _, pkg := range packages {
+		data, err := marshalJson([]*gocov.Package{pkg})
+		if err != nil {
+			return err
+		}
+		filename := filepath.Join(dir, url.PathEscape(pkg.Name)+".json")
+		if err := ioutil.WriteFile(filename, append(data, '\n'), 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeCoverage writes the coverage output of gocov test to the file
+// named by -o, or to stdout if it is "-". With -append, the coverage
+// is written to the end of the file in a single write, so that the
+// records of concurrent runs are not interleaved.
+func writeCoverage(data []byte) error {
+	data = append(data, '\n')
+	if *testOutputFlag == "-" {
+		_, err := os.Stdout.Write(data)
+		return err
+	}
+	if !*testAppendFlag {
+		return ioutil.WriteFile(*testOutputFlag, data, 0644)
+	}
+	file, err := os.OpenFile(*testOutputFlag, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// packageRun describes a run of go test for a single package.
+type packageRun struct {
+	pkg       string
+	coverFile string
+	args      []string
+
+	// label names the coverage to which the run contributes, such
+	// as "race", with -race-compare.
+	label string
+}
+
+// coverageVariant is the coverage of the runs with one label, output
+// with -race-compare alongside the coverage of all runs.
+type coverageVariant struct {
+	Label    string
+	Packages []*gocov.Package
+}
+
+// labeledCoverage returns the coverage of the runs with each label.
+// As with all runs, packages without tests have no profile.
+func labeledCoverage(runs []packageRun, labels []string) ([]coverageVariant, error) {
+	var variants []coverageVariant
+	for _, label := range labels {
+		var files []string
+		for _, run := range runs {
+			if _, err := os.Stat(run.coverFile); run.label == label && err == nil {
+				files = append(files, run.coverFile)
+			}
+		}
+		ps, err := mergeProfiles(files...)
+		if err == nil && *testFileFlag != "" {
+			ps, err = filterFile(ps, *testFileFlag)
+		}
+		if err != nil {
+			return nil, err
+		}
+		variants = append(variants, coverageVariant{label, ps})
+	}
+	return variants, nil
+}
+
+// testPackageRuns runs go test for each of runs, running as many at
+// once as -parallel-packages allows. Packages that time out are
+// returned. The remaining packages are still tested after a failure,
+// and the error of the first failed run is returned once all have
+// finished, unless -fail-fast is given: then further packages are not
+// started, and those being tested are stopped. With -resume, runs that
+// completed in a previous run are skipped, and those whose tests pass
+// are recorded in resume.
+func testPackageRuns(runs []packageRun, timer *timings, resume *resumeState) (timedOut []string, err error) {
+	parallel := *testParallelPackagesFlag
+	if parallel < 1 {
+		parallel = 1
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	errs := make([]error, len(runs))
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, parallel)
+	started := 0
+	for i, run := range runs {
+		if resume.done(run) {
+			fmt.Fprintf(os.Stderr, "skip\t%s (completed by a previous run with -resume)\n", run.pkg)
+			continue
+		}
+		sem <- struct{}{}
+		if ctx.Err() != nil {
+			<-sem
+			break
+		}
+		started = i + 1
+		wg.Add(1)
+		go func(i int, run packageRun) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			err := timer.time("test", run.pkg, func() error {
+				return goTest(ctx, run.pkg, run.coverFile, run.args)
+			})
+			if err == nil {
+				err = resume.record(run)
+			}
+			errs[i] = err
+			if err != nil && err != errPackageTimeout && err != errPackageCanceled && *testFailFastFlag {
+				cancel()
+			}
+		}(i, run)
+	}
+	wg.Wait()
+	for i, err := range errs {
+		if err == errPackageTimeout {
+			fmt.Fprintf(os.Stderr, "FAIL\t%s (timed out after %v)\n", runs[i].pkg, *testTimeoutPerPackageFlag)
+			timedOut = append(timedOut, runs[i].pkg)
+		}
+	}
+	for i, err := range errs {
+		if err == nil || err == errPackageTimeout || err == errPackageCanceled {
+			continue
+		}
+		if *testFailFastFlag {
+			var skipped []string
+			for j, run := range runs {
+				if errs[j] == errPackageCanceled || (j >= started && !resume.done(run)) {
+					skipped = append(skipped, run.pkg)
+				}
+			}
+			if len(skipped) > 0 {
+				fmt.Fprintf(os.Stderr, "skip\t%s (not tested after %s failed, with -fail-fast)\n",
+					strings.Join(skipped, ", "), runs[i].pkg)
+			}
+		}
+		return timedOut, err
+	}
+	return timedOut, nil
+}
+
+// errPackageTimeout is returned by goTest if the tests of a package
+// do not finish within -timeout-per-package.
+var errPackageTimeout = errors.New("package timed out")
+
+// errPackageCanceled is returned by goTest if the tests of a package
+// are stopped by the failure of another's with -fail-fast.
+var errPackageCanceled = errors.New("package canceled")
+
+// buildTests builds a test binary with coverage enabled for each
+// package with tests, writing it to the -outdir directory with the
+// same name that "go test -c" would use, and prints the path of each
+// binary built. Running a binary with -test.coverprofile writes a
+// profile that may be converted with "gocov convert".
+func buildTests(pkgs, args []string) error {
+	names := make(map[string]string)
+	for _, pkg := range pkgs {
+		name := path.Base(pkg) + ".test"
+		if runtime.GOOS == "windows" {
+			name += ".exe"
+		}
+		if other, ok := names[name]; ok {
+			return fmt.Errorf("test binaries for %s and %s would both be named %s", other, pkg, name)
+		}
+		names[name] = pkg
+		binary := filepath.Join(*testOutdirFlag, name)
+		cmdArgs := append([]string{"test", "-c", "-cover", "-o", binary}, withPackage(args, pkg)...)
+		cmd := exec.Command("go", cmdArgs...)
+		cmd.Env = testEnviron()
+		cmd.Stdout = os.Stderr
+		cmd.Stderr = os.Stderr
+		if err := cmd.Run(); err != nil {
+			return err
+		}
+		// Packages without tests have no test binary.
+		if _, err := os.Stat(binary); err == nil {
+			fmt.Println(binary)
+		}
+	}
+	return nil
+}
+
+// outputMu serializes the writing of buffered test output.
+var outputMu sync.Mutex
+
+// goTest runs "go test" for a single package, writing its coverage
+// profile to coverFile. If ctx is canceled, errPackageCanceled is
+// returned.
+func goTest(ctx context.Context, pkg, coverFile string, args []string) error {
+	if *testTimeoutPerPackageFlag > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, *testTimeoutPerPackageFlag)
+		defer cancel()
+	}
+	var binary *testBinary
+	if *testExitCoverageFlag {
+		var err error
+		if binary, err = buildTestBinary(pkg, coverFile, args); err != nil {
+			return err
+		}
+	}
+	var cmd *exec.Cmd
+	if binary != nil {
+		cmd = exec.CommandContext(ctx, binary.path, binary.args...)
+		cmd.Dir = binary.dir
+		cmd.Env = append(testEnviron(), "GOCOVERDIR="+binary.coverDir)
+	} else {
+		cmdArgs := append([]string{"test", "-coverprofile", coverFile}, withPackage(args, pkg)...)
+		cmd = exec.CommandContext(ctx, "go", cmdArgs...)
+		cmd.Env = testEnviron()
+	}
+	cmd.Stdin = nil
+	killProcessGroupOnCancel(cmd)
+	// Write all test command output to stderr so as not to interfere with
+	// the JSON coverage output. In quiet mode the output is buffered, and
+	// only written if the tests fail.
+	// When packages are tested in parallel, each package's output is
+	// buffered and written once it finishes, so that the output of
+	// different packages is not interleaved. All output is also
+	// recorded in testLog, if -archive is given.
+	output := newCappedBuffer(*testMaxTestOutputFlag)
+	buffered := *testQuietFlag || *testParallelPackagesFlag > 1
+	var w io.Writer = os.Stderr
+	if buffered {
+		w = output
+	} else if testLog != nil {
+		w = io.MultiWriter(w, testLog)
+	}
+	var passed passWriter
+	if binary != nil {
+		w = io.MultiWriter(w, &passed)
+	}
+	cmd.Stdout = w
+	cmd.Stderr = w
+	err := cmd.Run()
+	if binary != nil {
+		// The counters are written even if a test called os.Exit,
+		// which without go test's -test.paniconexit0 may exit with
+		// status 0 before the tests finish.
+		if writeErr := binary.writeProfile(coverFile); writeErr != nil && err == nil {
+			err = writeErr
+		}
+	}
+	if buffered {
+		outputMu.Lock()
+		if testLog != nil {
+			testLog.Write(output.Bytes())
+		}
+		if err != nil || !*testQuietFlag {
+			os.Stderr.Write(output.Bytes())
+		}
+		outputMu.Unlock()
+	}
+	if binary != nil && err == nil && !passed.passed {
+		fmt.Fprintf(os.Stderr, "warning: the tests of %s exited before they finished, such as by calling os.Exit(0); their coverage is partial\n", pkg)
+	}
+	if err != nil {
+		switch ctx.Err() {
+		case context.DeadlineExceeded:
+			return errPackageTimeout
+		case context.Canceled:
+			return errPackageCanceled
+		}
+	}
+	return err
+}
+
+// testBinary is a test binary built by buildTestBinary, and how to
+// run it.
+type testBinary struct {
+	path string
+	args []string
+
+	// dir is the directory of the package, in which go test would
+	// run the binary.
+	dir string
+
+	// coverDir is the directory to which the binary writes its
+	// coverage counters.
+	coverDir string
+}
+
+// buildTestBinary builds the test binary of pkg with coverage enabled,
+// alongside coverFile, for -exit-coverage. The flags of go test in args
+// are given to the go command or to the binary as appropriate. If the
+// package has no tests, and so no test binary, nil is returned.
+func buildTestBinary(pkg, coverFile string, args []string) (*testBinary, error) {
+	buildArgs, testArgs := testflag.SplitBuild(args)
+	base := strings.TrimSuffix(coverFile, ".cov")
+	binary := &testBinary{path: base + ".test", coverDir: base + ".cover"}
+	if runtime.GOOS == "windows" {
+		binary.path += ".exe"
+	}
+	cmdArgs := append([]string{"test", "-c", "-cover", "-o", binary.path}, withPackage(buildArgs, pkg)...)
+	cmd := exec.Command("go", cmdArgs...)
+	cmd.Env = testEnviron()
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(binary.path); err != nil {
+		return nil, nil
+	}
+	output, err := goList("{{.Dir}}", []string{pkg}, os.Stderr)
+	if err != nil {
+		return nil, err
+	}
+	binary.dir = strings.TrimSpace(string(output))
+	if err := os.Mkdir(binary.coverDir, 0755); err != nil {
+		return nil, err
+	}
+	binary.args = append([]string{"-test.gocoverdir=" + binary.coverDir}, testArgs...)
+	return binary, nil
+}
+
+// writeProfile converts the coverage counters written by the binary
+// to a coverage profile. If the binary wrote none, such as when it
+// failed to start, the profile is empty.
+func (b *testBinary) writeProfile(coverFile string) error {
+	cmd := exec.Command("go", "tool", "covdata", "textfmt", "-i", b.coverDir, "-o", coverFile)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("failed to convert coverage counters: %v\n%s", err, stderr.Bytes())
+	}
+	if _, err := os.Stat(coverFile); os.IsNotExist(err) {
+		return ioutil.WriteFile(coverFile, []byte("mode: set\n"), 0644)
+	}
+	return nil
+}
+
+// passWriter records whether the output of a test binary includes
+// the "PASS" line with which it reports that all of its tests passed.
+type passWriter struct {
+	line   []byte
+	passed bool
+}
+
+func (w *passWriter) Write(p []byte) (int, error) {
+	for _, c := range p {
+		if c != '\n' {
+			w.line = append(w.line, c)
+			continue
+		}
+		w.passed = w.passed || string(w.line) == "PASS"
+		w.line = w.line[:0]
+	}
+	return len(p), nil
+}
+
+// cappedBuffer buffers output up to a limit. Past the limit, only
+// the beginning and the end of the output are kept, as the end of go
+// test's output usually holds the failures.
+type cappedBuffer struct {
+	limit     int
+	head      []byte
+	tail      []byte
+	truncated int
+}
+
+// newCappedBuffer returns a cappedBuffer keeping up to limit bytes, or
+// all output if limit is 0.
+func newCappedBuffer(limit int) *cappedBuffer {
+	return &cappedBuffer{limit: limit}
+}
+
+func (b *cappedBuffer) Write(p []byte) (int, error) {
+	n := len(p)
+	if b.limit <= 0 {
+		b.head = append(b.head, p...)
+		return n, nil
+	}
+	if room := b.limit/2 - len(b.head); room > 0 {
+		if room > len(p) {
+			room = len(p)
+		}
+		b.head = append(b.head, p[:room]...)
+		p = p[room:]
+	}
+	b.tail = append(b.tail, p...)
+	if max := b.limit - b.limit/2; len(b.tail) > max {
+		b.truncated += len(b.tail) - max
+		b.tail = append(b.tail[:0], b.tail[len(b.tail)-max:]...)
+	}
+	return n, nil
+}
+
+// Bytes returns the output kept, marking where any was left out.
+func (b *cappedBuffer) Bytes() []byte {
+	if b.truncated == 0 {
+		return append(b.head[:len(b.head):len(b.head)], b.tail...)
+	}
+	marker := fmt.Sprintf("\n[output truncated: %d bytes omitted]\n", b.truncated)
+	output := append(b.head[:len(b.head):len(b.head)], marker...)
+	return append(output, b.tail...)
+}
+
+// lockedWriter serializes the writes to w, which may be made from
+// more than one goroutine.
+type lockedWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (w *lockedWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.w.Write(p)
+}
+
+// goTestJSON runs "go test -json" for a single package, writing its
+// coverage profile to coverFile, and returns the outcome of each of
+// the tests. The output of the tests is written as it would be by
+// goTest. The error returned by go test, if any, is returned along
+// with the results.
+func goTestJSON(pkg, coverFile string, args []string) ([]testjson.Result, error) {
+	cmdArgs := append([]string{"test", "-json", "-coverprofile", coverFile}, withPackage(args, pkg)...)
+	cmd := exec.Command("go", cmdArgs...)
+	cmd.Env = testEnviron()
+	cmd.Stdin = nil
+	// With -quiet, the stderr of go test is copied to output by a
+	// goroutine of os/exec while the output of the tests is decoded
+	// from stdout, so the writes are serialized.
+	output := newCappedBuffer(*testMaxTestOutputFlag)
+	quietOutput := &lockedWriter{w: output}
+	if *testQuietFlag {
+		cmd.Stderr = quietOutput
+	} else {
+		cmd.Stderr = os.Stderr
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	results, decodeErr := testjson.Decode(stdout, func(e testjson.Event) {
+		if e.Action != "output" {
+			return
+		}
+		if *testQuietFlag {
+			io.WriteString(quietOutput, e.Output)
+		} else {
+			os.Stderr.WriteString(e.Output)
+		}
+	})
+	err = cmd.Wait()
+	if err == nil {
+		err = decodeErr
+	}
+	if err != nil {
+		os.Stderr.Write(output.Bytes())
+	}
+	return results, err
+}
+
+// testCoverage records the coverage of a single test.
+type testCoverage struct {
+	// Package is the import path of the package containing the test.
+	Package string
+
+	// Name is the name of the test.
+	Name string
+
+	// Result is the outcome of the test: "pass", "fail" or "skip".
+	Result string
+
+	// Packages holds the coverage of the test.
+	Packages []*gocov.Package
+}
+
+// makeTempDir creates the temp directory in dir that holds the
+// coverage profiles. If name is non-empty, the directory is named
+// gocov-<name>, and it is an error for it to exist already, as it may
+// belong to a concurrent run; otherwise it is given a random name.
+func makeTempDir(dir, name string) (string, error) {
+	if name == "" {
+		return ioutil.TempDir(dir, "gocov")
+	}
+	if strings.ContainsAny(name, `/\`) || name == "." || name == ".." {
+		return "", fmt.Errorf("invalid -tmpname %q: must not be a path", name)
+	}
+	tmpDir := filepath.Join(dir, "gocov-"+name)
+	if err := os.Mkdir(tmpDir, 0700); err != nil {
+		if os.IsExist(err) {
+			return "", fmt.Errorf("temp directory %s already exists; it may be in use by another run with the same -tmpname", tmpDir)
+		}
+		return "", err
+	}
+	return tmpDir, nil
+}
+
+// runPerTest runs each test in pkgs individually, and outputs the
+// coverage and outcome of each test. Failing tests do not prevent
+// the remaining tests from being run.
+func runPerTest(tmpDir string, pkgs, args []string) error {
+	pattern, ok := flagValue(args, "run")
+	if !ok {
+		pattern = "."
+	}
+	tests := []testCoverage{}
+	var failed int
+	for _, pkg := range pkgs {
+		names, err := listTests(pkg, pattern, args)
+		if err != nil {
+			return err
+		}
+		for _, name := range names {
+			coverFile := filepath.Join(tmpDir, fmt.Sprintf("test%d.cov", len(tests)))
+			testArgs := append(append([]string{}, args...), "-run", "^"+name+"$")
+			results, err := goTestJSON(pkg, coverFile, testArgs)
+			result := testResult(results, name)
+			if result == "" {
+				if err == nil {
+					err = fmt.Errorf("no result for test %s in %s", name, pkg)
+				}
+				return err
+			}
+			if result == "fail" {
+				failed++
+			}
+			ps, err := mergeProfiles(coverFile)
+			if err != nil {
+				return err
+			}
+			tests = append(tests, testCoverage{Package: pkg, Name: name, Result: result, Packages: ps})
+		}
+	}
+	data, err := json.Marshal(struct{ Tests []testCoverage }{tests})
+	if err != nil {
+		return err
+	}
+	if err := writeCoverage(data); err != nil {
+		return err
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d tests failed", failed, len(tests))
+	}
+	return nil
+}
+
+// testResult returns the outcome of the named test, or "" if there
+// is none.
+func testResult(results []testjson.Result, name string) string {
+	for _, r := range results {
+		if r.Test == name {
+			return r.Action
+		}
+	}
+	return ""
+}
+
+// listTests returns the names of the tests, examples and fuzz
+// targets in pkg that match pattern.
+func listTests(pkg, pattern string, args []string) ([]string, error) {
+	var buf bytes.Buffer
+	cmdArgs := append([]string{"test", "-list", pattern}, withPackage(args, pkg)...)
+	cmd := exec.Command("go", cmdArgs...)
+	cmd.Env = testEnviron()
+	cmd.Stdout = &buf
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, line := range strings.Split(buf.String(), "\n") {
+		line = strings.TrimSpace(line)
+		for _, prefix := range []string{"Test", "Example", "Fuzz"} {
+			if strings.HasPrefix(line, prefix) && !strings.ContainsAny(line, " \t") {
+				names = append(names, line)
+				break
+			}
+		}
+	}
+	return names, nil
+}
+
+// flagValue returns the value of the last occurrence of the named
+// flag in args, which are in the form accepted by "go test".
+func flagValue(args []string, name string) (value string, ok bool) {
+	for i := 0; i < len(args); i++ {
+		arg := strings.TrimPrefix(args[i], "-")
+		if arg == args[i] {
+			continue
+		}
+		arg = strings.TrimPrefix(arg, "-")
+		arg = strings.TrimPrefix(arg, "test.")
+		switch {
+		case arg == name && i+1 < len(args):
+			value, ok = args[i+1], true
+			i++
+		case strings.HasPrefix(arg, name+"="):
+			value, ok = arg[len(name)+1:], true
+		}
+	}
+	return value, ok
 }
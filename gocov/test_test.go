@@ -0,0 +1,1486 @@
+// Copyright (c) 2026 The Gocov Authors.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/axw/gocov"
+)
+
+// captureOutput calls f, and returns everything written to
+// os.Stdout and os.Stderr while it ran.
+func captureOutput(t *testing.T, f func()) (stdout, stderr string) {
+	capture := func(file **os.File) func() string {
+		tmp, err := ioutil.TempFile("", "gocov")
+		if err != nil {
+			t.Fatal(err)
+		}
+		orig := *file
+		*file = tmp
+		return func() string {
+			*file = orig
+			defer os.Remove(tmp.Name())
+			defer tmp.Close()
+			data, err := ioutil.ReadFile(tmp.Name())
+			if err != nil {
+				t.Fatal(err)
+			}
+			return string(data)
+		}
+	}
+	restoreStdout := capture(&os.Stdout)
+	restoreStderr := capture(&os.Stderr)
+	defer func() {
+		stdout, stderr = restoreStdout(), restoreStderr()
+	}()
+	f()
+	return
+}
+
+// resetFlags restores the default values of all flags in fs.
+// Repeatable flags, which append on Set, must be reset separately.
+func resetFlags(fs *flag.FlagSet) {
+	fs.VisitAll(func(f *flag.Flag) {
+		if f.Value.String() != f.DefValue {
+			f.Value.Set(f.DefValue)
+		}
+	})
+}
+
+// testPackages runs "gocov test" with the given arguments, and
+// returns the resulting coverage.
+func testPackages(t *testing.T, args ...string) []*gocov.Package {
+	var err error
+	stdout, stderr := captureOutput(t, func() {
+		err = runTests(args)
+	})
+	if err != nil {
+		t.Fatalf("%v\n%s", err, stderr)
+	}
+	packages, err := unmarshalJson([]byte(stdout))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return packages
+}
+
+// statementsReached returns the number of statements reached in
+// each function of the packages.
+func statementsReached(packages []*gocov.Package) map[string]int {
+	reached := make(map[string]int)
+	for _, pkg := range packages {
+		for _, fn := range pkg.Functions {
+			var n int
+			for _, stmt := range fn.Statements {
+				if stmt.Reached > 0 {
+					n++
+				}
+			}
+			reached[fn.Name] += n
+		}
+	}
+	return reached
+}
+
+func TestExtractFlags(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	b := fs.Bool("b", false, "")
+	s := fs.String("s", "", "")
+	rest, err := extractFlags(fs, []string{
+		"-v", "-b", "--s", "value", "./...", "-run=X", "--", "-s=ignored",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !*b || *s != "value" {
+		t.Errorf("flags not set: b=%v, s=%q", *b, *s)
+	}
+	expect := []string{"-v", "./...", "-run=X", "--", "-s=ignored"}
+	if !reflect.DeepEqual(rest, expect) {
+		t.Errorf("got %q, expected %q", rest, expect)
+	}
+	if _, err := extractFlags(fs, []string{"-s"}); err == nil {
+		t.Error("expected an error for missing flag argument")
+	}
+
+	// Arguments for the test binary following -args are not parsed,
+	// even where they are named as flags of fs.
+	*s = ""
+	rest, err = extractFlags(fs, []string{"./...", "-args", "-s", "value", "-b"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *s != "" {
+		t.Errorf("flag following -args was set: s=%q", *s)
+	}
+	expect = []string{"./...", "-args", "-s", "value", "-b"}
+	if !reflect.DeepEqual(rest, expect) {
+		t.Errorf("got %q, expected %q", rest, expect)
+	}
+	if got := removeVerboseFlag([]string{"-v", "-args", "-v"}); !reflect.DeepEqual(got, []string{"-args", "-v"}) {
+		t.Errorf("removeVerboseFlag removed -v following -args: %q", got)
+	}
+}
+
+func TestRunTestsArgs(t *testing.T) {
+	// -env following -args is the test's own flag, not gocov's.
+	defer func() { testEnvFlag = envFlag{} }()
+	packages := testPackages(t, "./testdata/args", "-args", "-env=prod")
+	if reached := statementsReached(packages); reached["Greeting"] != 1 {
+		t.Errorf("Greeting not covered: %v", reached)
+	}
+	if len(testEnvFlag.values) != 0 {
+		t.Errorf("gocov's -env was set: %q", testEnvFlag.values)
+	}
+}
+
+func TestRunTestsWithoutGoTool(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gocov")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer os.Setenv("PATH", os.Getenv("PATH"))
+	os.Setenv("PATH", dir)
+
+	err = runTests([]string{"./..."})
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	if !strings.Contains(err.Error(), "go tool could not be found in PATH") {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if status := exitStatus(err); status != exitEnvironment {
+		t.Errorf("got exit status %d, expected %d", status, exitEnvironment)
+	}
+}
+
+func TestResolveTestdataPackage(t *testing.T) {
+	// Packages within testdata are skipped by "./..." patterns, but may be
+	// named explicitly by directory.
+	pkgs, err := resolvePackages([]string{"./testdata/simple"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := "github.com/axw/gocov/gocov/testdata/simple"
+	if len(pkgs) != 1 || pkgs[0] != expect {
+		t.Errorf("got %q, expected [%q]", pkgs, expect)
+	}
+}
+
+func TestRunTestsAbsoluteDir(t *testing.T) {
+	// A package may be named by its absolute directory, which is
+	// resolved to its import path by go list.
+	dir, err := filepath.Abs(filepath.Join("testdata", "simple"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	packages := testPackages(t, dir)
+	if len(packages) != 1 || packages[0].Name != "github.com/axw/gocov/gocov/testdata/simple" {
+		t.Errorf("unexpected packages: %v", packages)
+	}
+}
+
+func TestResolveDuplicatePackages(t *testing.T) {
+	pkgs, err := resolvePackages([]string{
+		"./testdata/simple",
+		"./testdata/simple",
+		"github.com/axw/gocov/gocov/testdata/simple",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := []string{"github.com/axw/gocov/gocov/testdata/simple"}
+	if !reflect.DeepEqual(pkgs, expect) {
+		t.Errorf("got %q, expected %q", pkgs, expect)
+	}
+}
+
+func TestResolvePackageGlobs(t *testing.T) {
+	// The fixture is a module of its own, as go list skips testdata.
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+	if err := os.Chdir("testdata/globs"); err != nil {
+		t.Fatal(err)
+	}
+	// The overlapping patterns name a/service twice, but it is only
+	// tested once.
+	pkgs, err := resolvePackages([]string{"./**/service", "a/*"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := []string{"example.com/globs/a/service", "example.com/globs/b/service"}
+	if !reflect.DeepEqual(pkgs, expect) {
+		t.Errorf("got %q, expected %q", pkgs, expect)
+	}
+	if _, err := resolvePackages([]string{"c/**"}); err == nil || err.Error() != "no packages match c/**" {
+		t.Errorf("expected an error for a pattern matching nothing, got %v", err)
+	}
+}
+
+func TestIsVendoredDir(t *testing.T) {
+	tests := []struct {
+		dir, root string
+		vendored  bool
+	}{
+		{"/src/project/vendor/example.com/dep", "/src/project", true},
+		{"/src/project/pkg", "/src/project", false},
+		{"/home/ci/vendor/project/pkg", "/home/ci/vendor/project", false},
+		{"/home/ci/vendor/project/vendor/dep", "/home/ci/vendor/project", true},
+		{"/src/project/pkg", "", false},
+	}
+	for _, test := range tests {
+		dir, root := filepath.FromSlash(test.dir), filepath.FromSlash(test.root)
+		if vendored := isVendoredDir(dir, root); vendored != test.vendored {
+			t.Errorf("isVendoredDir(%q, %q): got %v, expected %v", dir, root, vendored, test.vendored)
+		}
+	}
+}
+
+func TestResolveVendoredPackages(t *testing.T) {
+	defer resetFlags(testFlags)
+	// "./..." never matches vendored packages; they must be named.
+	vendoredArgs := []string{"./testdata/vendored", "./testdata/vendored/vendor/example.com/dep"}
+	var pkgs []string
+	var err error
+	_, stderr := captureOutput(t, func() {
+		pkgs, err = resolvePackages(vendoredArgs)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := []string{"github.com/axw/gocov/gocov/testdata/vendored"}
+	if !reflect.DeepEqual(pkgs, expect) {
+		t.Errorf("got %q, expected %q", pkgs, expect)
+	}
+	if !strings.Contains(stderr, "skipping vendored package") {
+		t.Errorf("expected a note about skipping vendored packages, got %q", stderr)
+	}
+
+	testFlags.Set("include-vendor", "true")
+	pkgs, err = resolvePackages(vendoredArgs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect = append(expect, "github.com/axw/gocov/gocov/testdata/vendored/vendor/example.com/dep")
+	if !reflect.DeepEqual(pkgs, expect) {
+		t.Errorf("got %q, expected %q", pkgs, expect)
+	}
+}
+
+func TestRunTestsQuiet(t *testing.T) {
+	defer resetFlags(testFlags)
+	var err error
+	stdout, stderr := captureOutput(t, func() {
+		err = runTests([]string{"-quiet", "-v", "./testdata/simple"})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stderr != "" {
+		t.Errorf("expected no test output, got %q", stderr)
+	}
+	if !strings.Contains(stdout, `"Name":"Covered"`) {
+		t.Errorf("expected coverage output, got %q", stdout)
+	}
+
+	stdout, stderr = captureOutput(t, func() {
+		err = runTests([]string{"-quiet", "./testdata/failing"})
+	})
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	if !strings.Contains(stderr, "Broken is broken") {
+		t.Errorf("expected test output, got %q", stderr)
+	}
+	if stdout != "" {
+		t.Errorf("expected no coverage output, got %q", stdout)
+	}
+}
+
+func TestRunTestsMaxTestOutput(t *testing.T) {
+	// The test prints about 1.5MB before failing. Only the beginning
+	// and the end are kept, and the end holds the failure.
+	defer resetFlags(testFlags)
+	var err error
+	_, stderr := captureOutput(t, func() {
+		err = runTests([]string{"-quiet", "-max-test-output", "4096", "./testdata/loud"})
+	})
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	if len(stderr) > 8192 {
+		t.Errorf("expected at most 4096 bytes of test output, got %d", len(stderr))
+	}
+	for _, s := range []string{"shouting 0\n", "[output truncated: ", "Shout is too quiet"} {
+		if !strings.Contains(stderr, s) {
+			t.Errorf("expected %q in test output:\n%s", s, stderr)
+		}
+	}
+
+	b := newCappedBuffer(10)
+	b.Write([]byte("abc"))
+	b.Write([]byte("de"))
+	if output := string(b.Bytes()); output != "abcde" {
+		t.Errorf("got %q, expected output within the limit unchanged", output)
+	}
+}
+
+func TestLockedWriter(t *testing.T) {
+	// goTestJSON writes to the buffer from its own goroutine and from
+	// that of os/exec copying stderr; run with -race to check them.
+	b := newCappedBuffer(0)
+	w := &lockedWriter{w: b}
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				w.Write([]byte("line\n"))
+			}
+		}()
+	}
+	wg.Wait()
+	if n := strings.Count(string(b.Bytes()), "line\n"); n != 400 {
+		t.Errorf("got %d lines, expected 400", n)
+	}
+}
+
+func TestRunTestsExitCoverage(t *testing.T) {
+	// TestExit calls os.Exit(0) before TestAfter runs. The coverage
+	// up to that point is kept, with a warning that it is partial.
+	defer resetFlags(testFlags)
+	var err error
+	stdout, stderr := captureOutput(t, func() {
+		err = runTests([]string{"-exit-coverage", "-v", "./testdata/exit"})
+	})
+	if err != nil {
+		t.Fatalf("%v\n%s", err, stderr)
+	}
+	if !strings.Contains(stderr, "warning: the tests of github.com/axw/gocov/gocov/testdata/exit exited before they finished") {
+		t.Errorf("expected a warning, got %q", stderr)
+	}
+	if !strings.Contains(stderr, "=== RUN   TestExit") {
+		t.Errorf("expected -v to be given to the test binary, got %q", stderr)
+	}
+	packages, err := unmarshalJson([]byte(stdout))
+	if err != nil {
+		t.Fatal(err)
+	}
+	reached := statementsReached(packages)
+	if reached["Before"] != 1 || reached["After"] != 0 {
+		t.Errorf("unexpected coverage: %v", reached)
+	}
+
+	// Without -exit-coverage, go test writes no coverage.
+	resetFlags(testFlags)
+	stdout, _ = captureOutput(t, func() {
+		err = runTests([]string{"./testdata/exit"})
+	})
+	if packages, _ := unmarshalJson([]byte(stdout)); err == nil || statementsReached(packages)["Before"] != 0 {
+		t.Errorf("expected failing tests without coverage, got %v and %q", err, stdout)
+	}
+}
+
+func TestRunTestsFile(t *testing.T) {
+	// The package is tested as a whole, but only the functions of
+	// b.go are output.
+	defer resetFlags(testFlags)
+	packages := testPackages(t, "-file", "sonarqube/b.go", "./testdata/sonarqube")
+	if len(packages) != 1 || len(packages[0].Functions) == 0 {
+		t.Fatalf("unexpected packages: %+v", packages)
+	}
+	for _, fn := range packages[0].Functions {
+		if filepath.Base(fn.File) != "b.go" {
+			t.Errorf("unexpected function %s of %s", fn.Name, fn.File)
+		}
+	}
+
+	resetFlags(testFlags)
+	var err error
+	captureOutput(t, func() {
+		err = runTests([]string{"-file", "c.go", "./testdata/sonarqube"})
+	})
+	if err == nil || !strings.Contains(err.Error(), "no statements in c.go") {
+		t.Errorf("expected an error for a file that was not tested, got %v", err)
+	}
+}
+
+func TestRunTestsResume(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gocov")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	resumeDir := filepath.Join(dir, "resume")
+
+	// The first run is interrupted by the failure of the second
+	// package, after the first has completed.
+	defer resetFlags(testFlags)
+	_, stderr := captureOutput(t, func() {
+		err = runTests([]string{"-resume", resumeDir, "./testdata/simple", "./testdata/failing"})
+	})
+	if err == nil {
+		t.Fatalf("expected an error\n%s", stderr)
+	}
+	if _, err := os.Stat(filepath.Join(resumeDir, resumeStateFile)); err != nil {
+		t.Fatalf("expected the completed runs to be recorded: %v", err)
+	}
+
+	// Resuming tests only the rest, and outputs the coverage of all.
+	resetFlags(testFlags)
+	var stdout string
+	stdout, stderr = captureOutput(t, func() {
+		err = runTests([]string{"-resume", resumeDir, "./testdata/simple", "./testdata/varinit"})
+	})
+	if err != nil {
+		t.Fatalf("%v\n%s", err, stderr)
+	}
+	if !strings.Contains(stderr, "skip\tgithub.com/axw/gocov/gocov/testdata/simple (completed by a previous run") {
+		t.Errorf("expected simple to be skipped, got %q", stderr)
+	}
+	if strings.Contains(stderr, "testdata/simple\t") || !strings.Contains(stderr, "testdata/varinit") {
+		t.Errorf("expected only varinit to be tested, got %q", stderr)
+	}
+	packages, err := unmarshalJson([]byte(stdout))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(packages) != 2 {
+		t.Errorf("expected the coverage of both packages, got %d", len(packages))
+	}
+	if _, err := os.Stat(resumeDir); !os.IsNotExist(err) {
+		t.Errorf("expected the -resume directory to be removed once the tests pass: %v", err)
+	}
+}
+
+func TestRunTestsResumeOtherFiles(t *testing.T) {
+	dir := t.TempDir()
+	defer resetFlags(testFlags)
+
+	// A directory holding files that gocov did not write is refused,
+	// and left as it is.
+	notes := filepath.Join(dir, "notes.txt")
+	if err := ioutil.WriteFile(notes, []byte("notes"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	var err error
+	_, stderr := captureOutput(t, func() {
+		err = runTests([]string{"-resume", dir, "./testdata/simple"})
+	})
+	if err == nil || !strings.Contains(err.Error(), "was not written by gocov test -resume") {
+		t.Fatalf("expected the directory to be refused, got %v\n%s", err, stderr)
+	}
+	if _, err := os.Stat(notes); err != nil {
+		t.Fatalf("expected %s to be kept: %v", notes, err)
+	}
+
+	// Files put in gocov's directory between runs are kept once the
+	// tests pass, along with the directory; gocov's own are removed.
+	resumeDir := filepath.Join(dir, "resume")
+	resetFlags(testFlags)
+	captureOutput(t, func() {
+		err = runTests([]string{"-resume", resumeDir, "./testdata/simple", "./testdata/failing"})
+	})
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	notes = filepath.Join(resumeDir, "notes.txt")
+	if err := ioutil.WriteFile(notes, []byte("notes"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	resetFlags(testFlags)
+	_, stderr = captureOutput(t, func() {
+		err = runTests([]string{"-resume", resumeDir, "./testdata/simple"})
+	})
+	if err != nil {
+		t.Fatalf("%v\n%s", err, stderr)
+	}
+	infos, err := ioutil.ReadDir(resumeDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(infos) != 1 || infos[0].Name() != "notes.txt" {
+		var names []string
+		for _, info := range infos {
+			names = append(names, info.Name())
+		}
+		t.Errorf("expected only notes.txt to be left, got %q", names)
+	}
+}
+
+func TestRunTestsFailFast(t *testing.T) {
+	// By default, the second package is still tested after the
+	// first fails.
+	defer resetFlags(testFlags)
+	var err error
+	stdout, stderr := captureOutput(t, func() {
+		err = runTests([]string{"./testdata/failing", "./testdata/simple"})
+	})
+	if err == nil || stdout != "" {
+		t.Fatalf("expected an error and no coverage, got %v and %q", err, stdout)
+	}
+	if !strings.Contains(stderr, "ok  \tgithub.com/axw/gocov/gocov/testdata/simple") {
+		t.Errorf("expected simple to be tested, got %q", stderr)
+	}
+
+	// With -fail-fast, it is not, and the coverage of the failing
+	// package is output.
+	resetFlags(testFlags)
+	stdout, stderr = captureOutput(t, func() {
+		err = runTests([]string{"-fail-fast", "./testdata/failing", "./testdata/simple"})
+	})
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	if !strings.Contains(stderr, "skip\tgithub.com/axw/gocov/gocov/testdata/simple (not tested after github.com/axw/gocov/gocov/testdata/failing failed") {
+		t.Errorf("expected simple to be skipped, got %q", stderr)
+	}
+	packages, err := unmarshalJson([]byte(stdout))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(packages) != 1 || packages[0].Name != "github.com/axw/gocov/gocov/testdata/failing" {
+		t.Errorf("expected the coverage of only the failing package, got %+v", packages)
+	}
+
+	// A package being tested in parallel is stopped, rather than
+	// waited for, when another fails.
+	resetFlags(testFlags)
+	start := time.Now()
+	_, stderr = captureOutput(t, func() {
+		err = runTests([]string{"-fail-fast", "-parallel-packages", "2", "./testdata/slow", "./testdata/failing"})
+	})
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	if elapsed := time.Since(start); elapsed > 30*time.Second {
+		t.Errorf("expected slow to be stopped, but the tests took %v", elapsed)
+	}
+	if !strings.Contains(stderr, "skip\tgithub.com/axw/gocov/gocov/testdata/slow (not tested after") {
+		t.Errorf("expected slow to be stopped, got %q", stderr)
+	}
+}
+
+func TestRunTestsRaceCompare(t *testing.T) {
+	if output, err := exec.Command("go", "env", "CGO_ENABLED").Output(); err != nil || strings.TrimSpace(string(output)) != "1" {
+		t.Skip("-race requires cgo")
+	}
+	defer resetFlags(testFlags)
+	var err error
+	stdout, stderr := captureOutput(t, func() {
+		err = runTests([]string{"-race-compare", "./testdata/simple"})
+	})
+	if err != nil {
+		t.Fatalf("%v\n%s", err, stderr)
+	}
+	var result struct {
+		Packages []*gocov.Package
+		Variants []coverageVariant
+	}
+	if err := json.Unmarshal([]byte(stdout), &result); err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Variants) != 2 || result.Variants[0].Label != "normal" || result.Variants[1].Label != "race" {
+		t.Fatalf("unexpected variants: %+v", result.Variants)
+	}
+	// The union is that of the two, which cover the same statements
+	// of the simple package.
+	union := statementsReached(result.Packages)
+	for _, variant := range result.Variants {
+		if reached := statementsReached(variant.Packages); !reflect.DeepEqual(reached, union) {
+			t.Errorf("%s: got coverage %v, expected %v", variant.Label, reached, union)
+		}
+	}
+	if union["Covered"] == 0 {
+		t.Errorf("unexpected coverage: %v", union)
+	}
+
+	resetFlags(testFlags)
+	captureOutput(t, func() {
+		err = runTests([]string{"-race-compare", "-race", "./testdata/simple"})
+	})
+	if err == nil {
+		t.Error("expected an error for -race with -race-compare")
+	}
+}
+
+func TestRunTestsDeferred(t *testing.T) {
+	packages := testPackages(t, "./testdata/deferred")
+	reached := statementsReached(packages)
+	expect := map[string]int{
+		// The panic is reached, and the deferred function
+		// literal runs while the panic unwinds.
+		"Recover": 2,
+		"@5:8":    1,
+	}
+	if !reflect.DeepEqual(reached, expect) {
+		t.Errorf("got %v, expected %v", reached, expect)
+	}
+}
+
+func TestRunTestsServer(t *testing.T) {
+	// The handler only runs in goroutines started by the server in
+	// TestMain, and its coverage is recorded when m.Run returns.
+	packages := testPackages(t, "./testdata/server")
+	reached := statementsReached(packages)
+	if expect := map[string]int{"Hello": 3}; !reflect.DeepEqual(reached, expect) {
+		t.Errorf("got %v, expected %v", reached, expect)
+	}
+}
+
+func TestRemoveVerboseFlag(t *testing.T) {
+	args := []string{
+		"-v", "--v", "-test.v", "--test.v", "-v=true", "--test.v=true",
+		"-v=false", "-run", "X", "--", "-v",
+	}
+	expect := []string{"-v=false", "-run", "X", "--", "-v"}
+	if result := removeVerboseFlag(args); !reflect.DeepEqual(result, expect) {
+		t.Errorf("got %q, expected %q", result, expect)
+	}
+}
+
+func TestRunTestsSwitches(t *testing.T) {
+	packages := testPackages(t, "./testdata/switches")
+	reached := make(map[int]int64)
+	for _, fn := range packages[0].Functions {
+		for _, stmt := range fn.Statements {
+			line, _ := offsetLine(t, fn.File, stmt.Start)
+			reached[line] += stmt.Reached
+		}
+	}
+	// Each arm of the switches is counted separately, including
+	// defaults and arms with several types or values.
+	for line, expect := range map[int]bool{
+		7:  true,  // if v < 0
+		8:  false, // return "negative int"
+		10: true,  // return "int"
+		12: true,  // return "text"
+		14: false, // return "nil"
+		16: true,  // _ = v
+		17: true,  // return "other"
+		25: false, // return "#f00"
+		27: true,  // return "#0f0"
+		29: false, // return ""
+	} {
+		if (reached[line] > 0) != expect {
+			t.Errorf("line %d: reached %d times", line, reached[line])
+		}
+	}
+}
+
+func TestRunTestsBlankAssignments(t *testing.T) {
+	packages := testPackages(t, "./testdata/blank")
+	reached := make(map[int]int64)
+	var statements int
+	for _, fn := range packages[0].Functions {
+		for _, stmt := range fn.Statements {
+			line, _ := offsetLine(t, fn.File, stmt.Start)
+			reached[line] += stmt.Reached
+			statements++
+		}
+	}
+	// Assignments to the blank identifier are statements like any
+	// other, counted as go tool cover counts them.
+	if statements != 5 {
+		t.Errorf("expected 5 statements, got %d", statements)
+	}
+	for line, expect := range map[int]bool{
+		7:  true,  // _ = len(s)
+		8:  true,  // if _, err := strconv.Atoi(s); err != nil
+		9:  true,  // return 0
+		11: false, // n, _ := strconv.Atoi(s)
+		12: false, // return n
+	} {
+		if (reached[line] > 0) != expect {
+			t.Errorf("line %d: reached %d times", line, reached[line])
+		}
+	}
+}
+
+func TestRunTestsCoverMode(t *testing.T) {
+	// -covermode is passed to go test, and determines whether the
+	// statement of Hit, called by 100 goroutines, records only that
+	// it was reached, or the number of times.
+	for _, test := range []struct {
+		mode     string
+		min, max int64
+	}{
+		{"set", 1, 1},
+		{"atomic", 100, 100},
+		// Increments by concurrent goroutines may be lost.
+		{"count", 1, 100},
+	} {
+		packages := testPackages(t, "./testdata/modes", "-covermode="+test.mode)
+		reached := packages[0].Functions[0].Statements[0].Reached
+		if reached < test.min || reached > test.max {
+			t.Errorf("-covermode=%s: reached %d times, expected %d-%d", test.mode, reached, test.min, test.max)
+		}
+	}
+}
+
+// offsetLine returns the line and column of offset in the named file.
+func offsetLine(t *testing.T, filename string, offset int) (line, col int) {
+	file, err := newSourceFiles().file(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return offsetPosition(file, offset)
+}
+
+func TestFlagValue(t *testing.T) {
+	tests := []struct {
+		args  []string
+		value string
+		ok    bool
+	}{
+		{[]string{"-v"}, "", false},
+		{[]string{"-run", "X"}, "X", true},
+		{[]string{"--run=X", "-test.run", "Y"}, "Y", true},
+		{[]string{"-runner=X"}, "", false},
+	}
+	for _, test := range tests {
+		value, ok := flagValue(test.args, "run")
+		if value != test.value || ok != test.ok {
+			t.Errorf("flagValue(%q): got (%q, %v), expected (%q, %v)",
+				test.args, value, ok, test.value, test.ok)
+		}
+	}
+}
+
+func TestRunTestsPerTest(t *testing.T) {
+	defer resetFlags(testFlags)
+	var err error
+	stdout, stderr := captureOutput(t, func() {
+		err = runTests([]string{"-per-test", "./testdata/pertest"})
+	})
+	if err != nil {
+		t.Fatalf("%v\n%s", err, stderr)
+	}
+	var result struct{ Tests []testCoverage }
+	if err := json.Unmarshal([]byte(stdout), &result); err != nil {
+		t.Fatal(err)
+	}
+	reached := make(map[string]map[string]int)
+	for _, test := range result.Tests {
+		if test.Package != "github.com/axw/gocov/gocov/testdata/pertest" {
+			t.Errorf("unexpected package %q", test.Package)
+		}
+		if test.Result != "pass" {
+			t.Errorf("%s: unexpected result %q", test.Name, test.Result)
+		}
+		reached[test.Name] = statementsReached(test.Packages)
+	}
+	expect := map[string]map[string]int{
+		"TestF": {"F": 1, "G": 0},
+		"TestG": {"F": 0, "G": 1},
+	}
+	if !reflect.DeepEqual(reached, expect) {
+		t.Errorf("got %v, expected %v", reached, expect)
+	}
+}
+
+func TestRunTestsLineDirective(t *testing.T) {
+	// Coverage profiles record positions in the original file,
+	// ignoring //line directives, and name the directive's target.
+	// Statements are reported against the Go file holding them, as
+	// gocov's output locates statements by offsets into that file.
+	packages := testPackages(t, "./testdata/linedirective")
+	reached := statementsReached(packages)
+	expect := map[string]int{"Parse": 2}
+	if !reflect.DeepEqual(reached, expect) {
+		t.Errorf("got %v, expected %v", reached, expect)
+	}
+	if file := packages[0].Functions[0].File; filepath.Base(file) != "linedirective.go" {
+		t.Errorf("unexpected file %q", file)
+	}
+}
+
+func TestRunTestsDeterministic(t *testing.T) {
+	args := []string{"./testdata/simple", "./testdata/pertest", "./testdata/deferred"}
+	first, stderr := captureOutput(t, func() {
+		if err := runTests(args); err != nil {
+			t.Error(err)
+		}
+	})
+	second, _ := captureOutput(t, func() {
+		if err := runTests(args); err != nil {
+			t.Error(err)
+		}
+	})
+	if t.Failed() {
+		t.Fatal(stderr)
+	}
+	if first != second {
+		t.Errorf("output differs between runs:\n%s\n%s", first, second)
+	}
+}
+
+func TestSetenv(t *testing.T) {
+	env := setenv([]string{"A=1", "AB=2", "B=3"}, "A", "4")
+	expect := []string{"AB=2", "B=3", "A=4"}
+	if !reflect.DeepEqual(env, expect) {
+		t.Errorf("got %q, expected %q", env, expect)
+	}
+}
+
+func TestRunTestsEnv(t *testing.T) {
+	defer resetFlags(testFlags)
+	defer func() { testEnvFlag = envFlag{} }()
+	defer os.Unsetenv("GOCOV_TEST_VALUE")
+	os.Setenv("GOCOV_TEST_VALUE", "inherited")
+
+	packages := testPackages(t, "-quiet", "-env", "GOCOV_TEST_VALUE=a=b c", "./testdata/env")
+	if reached := statementsReached(packages); reached["Value"] != 1 {
+		t.Errorf("unexpected coverage: %v", reached)
+	}
+	if err := testFlags.Set("env", "invalid"); err == nil {
+		t.Error("expected an error for an invalid -env value")
+	}
+}
+
+func TestRunTestsFuzz(t *testing.T) {
+	// Fuzz targets are run with their seed corpus.
+	packages := testPackages(t, "./testdata/fuzz")
+	if reached := statementsReached(packages); reached["Trim"] != 2 {
+		t.Errorf("unexpected coverage: %v", reached)
+	}
+
+	err := runTests([]string{"-fuzz", "FuzzTrim", "-fuzztime", "1x", "./testdata/fuzz"})
+	if err == nil || !strings.Contains(err.Error(), "-fuzz") {
+		t.Errorf("expected an error for -fuzz, got %v", err)
+	}
+}
+
+func TestRunTestsPerTestFailure(t *testing.T) {
+	defer resetFlags(testFlags)
+	var err error
+	stdout, stderr := captureOutput(t, func() {
+		err = runTests([]string{"-per-test", "./testdata/failing", "./testdata/simple"})
+	})
+	if err == nil || err.Error() != "1 of 2 tests failed" {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if !strings.Contains(stderr, "Broken is broken") {
+		t.Errorf("expected test output, got %q", stderr)
+	}
+	var result struct{ Tests []testCoverage }
+	if err := json.Unmarshal([]byte(stdout), &result); err != nil {
+		t.Fatal(err)
+	}
+	results := make(map[string]string)
+	for _, test := range result.Tests {
+		results[test.Name] = test.Result
+	}
+	expect := map[string]string{"TestBroken": "fail", "TestCovered": "pass"}
+	if !reflect.DeepEqual(results, expect) {
+		t.Errorf("got %v, expected %v", results, expect)
+	}
+}
+
+func TestRunTestsNoTest(t *testing.T) {
+	defer resetFlags(testFlags)
+	dir, err := ioutil.TempDir("", "gocov")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	stdout, stderr := captureOutput(t, func() {
+		err = runTests([]string{"-no-test", "-outdir", dir, "./testdata/simple", "./testdata/vendored"})
+	})
+	if err != nil {
+		t.Fatalf("%v\n%s", err, stderr)
+	}
+	binary := filepath.Join(dir, "simple.test")
+	if runtime.GOOS == "windows" {
+		binary += ".exe"
+	}
+	// The vendored package has no tests, and so no test binary.
+	if stdout != binary+"\n" {
+		t.Errorf("got %q, expected %q", stdout, binary+"\n")
+	}
+
+	// The binary may be run later, and its profile converted.
+	coverFile := filepath.Join(dir, "simple.cov")
+	cmd := exec.Command(binary, "-test.coverprofile", coverFile)
+	cmd.Dir = "testdata/simple"
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("%v\n%s", err, output)
+	}
+	packages, err := mergeProfiles(coverFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reached := statementsReached(packages); reached["Covered"] != 2 {
+		t.Errorf("unexpected coverage: %v", reached)
+	}
+}
+
+func TestRunTestsTags(t *testing.T) {
+	defer func() { testTagsFlag = tagsFlag{} }()
+	defer resetFlags(testFlags)
+	packages := testPackages(t, "-tags", "a", "-tags", "b", "./testdata/tags")
+	expected := map[string]int{"A": 1, "B": 1, "Common": 3}
+	if reached := statementsReached(packages); !reflect.DeepEqual(reached, expected) {
+		t.Errorf("got %v, expected %v", reached, expected)
+	}
+}
+
+func TestRunTestsTimeoutPerPackage(t *testing.T) {
+	defer resetFlags(testFlags)
+	defer func() { testEnvFlag = envFlag{} }()
+	dir, err := ioutil.TempDir("", "gocov")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	pidFile := filepath.Join(dir, "pid")
+
+	// The limit includes building each package, so leave a margin
+	// for slow machines; the slow package otherwise runs for a minute.
+	stdout, stderr := captureOutput(t, func() {
+		err = runTests([]string{
+			"-timeout-per-package", "15s", "-env", "GOCOV_TEST_PID=" + pidFile,
+			"./testdata/slow", "./testdata/simple",
+		})
+	})
+	if err == nil || !strings.Contains(err.Error(), "1 of 2 packages timed out: github.com/axw/gocov/gocov/testdata/slow") {
+		t.Fatalf("unexpected error: %v\n%s", err, stderr)
+	}
+	// The remaining packages are still tested.
+	packages, err := unmarshalJson([]byte(stdout))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(packages) != 1 || packages[0].Name != "github.com/axw/gocov/gocov/testdata/simple" {
+		t.Errorf("unexpected packages: %v", packages)
+	}
+
+	// The test binary is killed along with go test.
+	data, err := ioutil.ReadFile(pidFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pid, err := strconv.Atoi(string(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if processRunning(pid) {
+		if p, err := os.FindProcess(pid); err == nil {
+			p.Kill()
+		}
+		t.Errorf("test binary %d is still running", pid)
+	}
+}
+
+// processRunning reports whether the process pid is still running,
+// giving it a few seconds to exit. It always reports false where
+// processes cannot be signalled, such as on Windows.
+func processRunning(pid int) bool {
+	for i := 0; i < 50; i++ {
+		p, err := os.FindProcess(pid)
+		if err != nil || p.Signal(syscall.Signal(0)) != nil {
+			return false
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	return true
+}
+
+func TestRunTestsPackagesFile(t *testing.T) {
+	defer resetFlags(testFlags)
+	stdin, err := ioutil.TempFile("", "gocov")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(stdin.Name())
+	defer stdin.Close()
+	_, err = stdin.WriteString("./testdata/simple\n\n./testdata/pertest\n./testdata/simple\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := stdin.Seek(0, 0); err != nil {
+		t.Fatal(err)
+	}
+	orig := os.Stdin
+	os.Stdin = stdin
+	defer func() { os.Stdin = orig }()
+
+	packages := testPackages(t, "-packages-file", "-")
+	var names []string
+	for _, pkg := range packages {
+		names = append(names, pkg.Name)
+	}
+	expected := []string{
+		"github.com/axw/gocov/gocov/testdata/pertest",
+		"github.com/axw/gocov/gocov/testdata/simple",
+	}
+	if !reflect.DeepEqual(names, expected) {
+		t.Errorf("got %v, expected %v", names, expected)
+	}
+}
+
+func TestRunTestsTimings(t *testing.T) {
+	defer resetFlags(testFlags)
+	var err error
+	_, stderr := captureOutput(t, func() {
+		err = runTests([]string{"-timings", "./testdata/simple"})
+	})
+	if err != nil {
+		t.Fatalf("%v\n%s", err, stderr)
+	}
+	for _, prefix := range []string{
+		"PHASE ",
+		"resolve ",
+		"test    github.com/axw/gocov/gocov/testdata/simple ",
+		"convert ",
+		"total ",
+	} {
+		if !strings.Contains(stderr, "\n"+prefix) && !strings.HasPrefix(stderr, prefix) {
+			t.Errorf("missing %q in timings:\n%s", prefix, stderr)
+		}
+	}
+}
+
+func TestRunTestsParallelPackages(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gocov")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	run := func(parallel string) string {
+		defer resetFlags(testFlags)
+		defer func() { testEnvFlag = envFlag{} }()
+		logFile := filepath.Join(dir, "log"+parallel)
+		packages := testPackages(t, "-parallel-packages", parallel,
+			"-env", "GOCOV_TEST_LOG="+logFile,
+			"./testdata/serial/a", "./testdata/serial/b", "-count=1")
+		if len(packages) != 2 {
+			t.Errorf("-parallel-packages %s: expected coverage of 2 packages, got %d", parallel, len(packages))
+		}
+		data, err := ioutil.ReadFile(logFile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(data)
+	}
+
+	// With one package at a time, each must finish before the
+	// next starts.
+	if events := run("1"); events != "start a\nend a\nstart b\nend b\n" {
+		t.Errorf("runs were not serialized:\n%s", events)
+	}
+	// With two, both start before either ends.
+	events := run("2")
+	if !strings.HasPrefix(events, "start a\nstart b\n") && !strings.HasPrefix(events, "start b\nstart a\n") {
+		t.Errorf("runs did not overlap:\n%s", events)
+	}
+}
+
+func TestRunTestsOutput(t *testing.T) {
+	defer resetFlags(testFlags)
+	dir, err := ioutil.TempDir("", "gocov")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// With -o -, stdout holds only the coverage.
+	stdout, stderr := captureOutput(t, func() {
+		err = runTests([]string{"-o", "-", "-v", "./testdata/simple"})
+	})
+	if err != nil {
+		t.Fatalf("%v\n%s", err, stderr)
+	}
+	if _, err := unmarshalJson([]byte(stdout)); err != nil {
+		t.Errorf("stdout is not coverage: %v\n%s", err, stdout)
+	}
+	if !strings.Contains(stderr, "--- PASS: TestCovered") {
+		t.Errorf("expected test output on stderr, got:\n%s", stderr)
+	}
+
+	output := filepath.Join(dir, "coverage.json")
+	stdout, stderr = captureOutput(t, func() {
+		err = runTests([]string{"-o", output, "./testdata/simple"})
+	})
+	if err != nil {
+		t.Fatalf("%v\n%s", err, stderr)
+	}
+	if stdout != "" {
+		t.Errorf("unexpected output on stdout:\n%s", stdout)
+	}
+	data, err := ioutil.ReadFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	packages, err := unmarshalJson(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reached := statementsReached(packages); reached["Covered"] != 2 {
+		t.Errorf("unexpected coverage: %v", reached)
+	}
+}
+
+func TestRunTestsCgo(t *testing.T) {
+	defer resetFlags(testFlags)
+	defer func() { testCgoFlag = cgoFlag{} }()
+
+	tests := []struct {
+		flag     string
+		function string
+		env      string
+	}{
+		{"-cgo=false", "WithoutCgo", "CGO_ENABLED=0"},
+		{"-cgo=true", "WithCgo", "CGO_ENABLED=1"},
+	}
+	for _, test := range tests {
+		testCgoFlag = cgoFlag{}
+		packages := testPackages(t, "-quiet", test.flag, "./testdata/cgo")
+		if reached := statementsReached(packages); reached[test.function] != 1 {
+			t.Errorf("%s: unexpected coverage: %v", test.flag, reached)
+		}
+		if env := testEnviron(); env[len(env)-1] != test.env {
+			t.Errorf("%s: expected %s, got %q", test.flag, test.env, env)
+		}
+	}
+
+	testCgoFlag = cgoFlag{}
+	defer func() { testEnvFlag = envFlag{} }()
+	err := runTests([]string{"-cgo=false", "-env", "CGO_ENABLED=1", "./testdata/cgo"})
+	if err == nil || !strings.Contains(err.Error(), "-cgo") {
+		t.Errorf("expected an error for -cgo with -env CGO_ENABLED, got %v", err)
+	}
+}
+
+func TestRunTestsAppend(t *testing.T) {
+	defer resetFlags(testFlags)
+	dir, err := ioutil.TempDir("", "gocov")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// Each run appends a record, and the records are merged when read.
+	output := filepath.Join(dir, "coverage.json")
+	for i := 0; i < 3; i++ {
+		_, stderr := captureOutput(t, func() {
+			err = runTests([]string{"-o", output, "-append", "./testdata/simple"})
+		})
+		if err != nil {
+			t.Fatalf("%v\n%s", err, stderr)
+		}
+	}
+	data, err := ioutil.ReadFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Count(string(data), "\n"); lines != 3 {
+		t.Errorf("expected 3 records, got %d:\n%s", lines, data)
+	}
+	packages, err := unmarshalJson(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(packages) != 1 {
+		t.Errorf("expected the records to be merged into 1 package, got %d", len(packages))
+	}
+	var hits int64
+	for _, fn := range packages[0].Functions {
+		for _, stmt := range fn.Statements {
+			hits += stmt.Reached
+		}
+	}
+	if hits != 6 {
+		t.Errorf("expected 3 runs of 2 statements, got %d hits", hits)
+	}
+
+	resetFlags(testFlags)
+	if err := runTests([]string{"-append", "./testdata/simple"}); err == nil {
+		t.Error("expected an error for -append without -o")
+	}
+}
+
+func TestRunTestsVarInitializer(t *testing.T) {
+	// Closures in package-level variable initializers are reported
+	// as functions named by their position, like other literals;
+	// the one called during initialization is covered.
+	packages := testPackages(t, "./testdata/varinit")
+	reached := statementsReached(packages)
+	if reached["@4:13"] != 4 || reached["@13:14"] != 0 {
+		t.Errorf("unexpected coverage: %v", reached)
+	}
+	if _, ok := reached["@13:14"]; !ok {
+		t.Errorf("uncalled closure not reported: %v", reached)
+	}
+}
+
+func TestRunTestsCompositeLiteralClosures(t *testing.T) {
+	// Each closure within a composite literal, at package or function
+	// scope, is counted in its own right rather than as part of the
+	// statement containing the literal.
+	packages := testPackages(t, "./testdata/complit")
+	reached := statementsReached(packages)
+	expected := map[string]int{"@7:11": 1, "@10:11": 0, "@18:3": 1, "@21:3": 0, "Run": 2}
+	if !reflect.DeepEqual(reached, expected) {
+		t.Errorf("got coverage %v, expected %v", reached, expected)
+	}
+}
+
+func TestRunTestsReflection(t *testing.T) {
+	// Greet is only called through reflect.Value.Call, but its body
+	// is instrumented like any other, so it is covered.
+	packages := testPackages(t, "./testdata/reflect")
+	reached := statementsReached(packages)
+	expected := map[string]int{"Handler.Greet": 1, "Handler.Ignored": 0, "Dispatch": 2}
+	if !reflect.DeepEqual(reached, expected) {
+		t.Errorf("got coverage %v, expected %v", reached, expected)
+	}
+}
+
+func TestRunTestsTypeAssertions(t *testing.T) {
+	// The two-value type assertion still reports failure rather than
+	// panicking once instrumented, and each form is counted.
+	packages := testPackages(t, "./testdata/assert")
+	reached := statementsReached(packages)
+	expected := map[string]int{"Int": 2, "MustInt": 1, "Truncate": 2}
+	if !reflect.DeepEqual(reached, expected) {
+		t.Errorf("got coverage %v, expected %v", reached, expected)
+	}
+}
+
+func TestRunTestsSplitOutput(t *testing.T) {
+	defer resetFlags(testFlags)
+	dir, err := ioutil.TempDir("", "gocov")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	const pkgA = "github.com/axw/gocov/gocov/testdata/simple"
+	const pkgB = "github.com/axw/gocov/gocov/testdata/varinit"
+	output := filepath.Join(dir, "coverage")
+	stdout, stderr := captureOutput(t, func() {
+		err = runTests([]string{"-split-output", output, "./testdata/simple", "./testdata/varinit"})
+	})
+	if err != nil {
+		t.Fatalf("%v\n%s", err, stderr)
+	}
+	if stdout != "" {
+		t.Errorf("unexpected output on stdout:\n%s", stdout)
+	}
+	files, err := filepath.Glob(filepath.Join(output, "*.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 2 {
+		t.Fatalf("expected a file per package, got %q", files)
+	}
+	for _, name := range []string{pkgA, pkgB} {
+		data, err := ioutil.ReadFile(filepath.Join(output, url.PathEscape(name)+".json"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		packages, err := unmarshalJson(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(packages) != 1 || packages[0].Name != name {
+			t.Errorf("expected only %s, got %+v", name, packages)
+		}
+	}
+
+	// The report of the directory combines the files.
+	rc, stdout, stderr := runReport(t, "-format", "json", output)
+	if rc != 0 {
+		t.Fatalf("report failed: %s", stderr)
+	}
+	var report jsonReport
+	if err := json.Unmarshal([]byte(stdout), &report); err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Packages) != 2 {
+		t.Errorf("expected 2 packages in the report, got %+v", report.Packages)
+	}
+
+	resetFlags(testFlags)
+	err = runTests([]string{"-split-output", output, "-o", filepath.Join(dir, "c.json"), "./testdata/simple"})
+	if err == nil {
+		t.Error("expected an error for -split-output with -o")
+	}
+}
+
+func TestRunTestsExamples(t *testing.T) {
+	// Running the tests with coverage must not change the output
+	// of examples, or their results, from a plain go test.
+	cmd := exec.Command("go", "test", "-v", "-count=1", "./testdata/example")
+	plain, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("%v\n%s", err, plain)
+	}
+
+	stdout, stderr := captureOutput(t, func() {
+		err = runTests([]string{"./testdata/example", "-v", "-count=1"})
+	})
+	if err != nil {
+		t.Fatalf("%v\n%s", err, stderr)
+	}
+	results := func(output string) []string {
+		var lines []string
+		for _, line := range strings.Split(output, "\n") {
+			if strings.HasPrefix(line, "--- ") {
+				// Drop the elapsed time.
+				if paren := strings.Index(line, " ("); paren >= 0 {
+					line = line[:paren]
+				}
+				lines = append(lines, line)
+			}
+		}
+		return lines
+	}
+	expect := []string{"--- PASS: ExampleGreet", "--- PASS: ExampleGreet_unordered"}
+	if got := results(string(plain)); !reflect.DeepEqual(got, expect) {
+		t.Fatalf("unexpected results without coverage: %q", got)
+	}
+	if got := results(stderr); !reflect.DeepEqual(got, expect) {
+		t.Errorf("got results %q with coverage, expected %q", got, expect)
+	}
+	packages, err := unmarshalJson([]byte(stdout))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reached := statementsReached(packages); reached["Greet"] != 3 {
+		t.Errorf("unexpected coverage: %v", reached)
+	}
+}
+
+func TestRunTestsPostHook(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the hook is a shell script")
+	}
+	defer resetFlags(testFlags)
+	dir, err := ioutil.TempDir("", "gocov")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	record := filepath.Join(dir, "record")
+	hook := `echo "$1 $GOCOV_OUTPUT" > ` + record + `; cp "$1" ` + record + `.json`
+
+	// With -o, the hook is given the file.
+	output := filepath.Join(dir, "coverage.json")
+	_, stderr := captureOutput(t, func() {
+		err = runTests([]string{"-o", output, "-post-hook", hook, "./testdata/simple"})
+	})
+	if err != nil {
+		t.Fatalf("%v\n%s", err, stderr)
+	}
+	data, err := ioutil.ReadFile(record)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expect := output + " " + output + "\n"; string(data) != expect {
+		t.Errorf("hook recorded %q, expected %q", data, expect)
+	}
+
+	// With stdout, the hook is given a copy of the coverage.
+	resetFlags(testFlags)
+	stdout, stderr := captureOutput(t, func() {
+		err = runTests([]string{"-post-hook", hook, "./testdata/simple"})
+	})
+	if err != nil {
+		t.Fatalf("%v\n%s", err, stderr)
+	}
+	data, err = ioutil.ReadFile(record + ".json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != stdout {
+		t.Errorf("hook was given:\n%s\nexpected the coverage:\n%s", data, stdout)
+	}
+
+	// A failing hook only fails the run with -post-hook-required.
+	resetFlags(testFlags)
+	_, stderr = captureOutput(t, func() {
+		err = runTests([]string{"-o", output, "-post-hook", "exit 3", "./testdata/simple"})
+	})
+	if err != nil || !strings.Contains(stderr, "warning: -post-hook failed") {
+		t.Errorf("expected a warning, got %v\n%s", err, stderr)
+	}
+	resetFlags(testFlags)
+	captureOutput(t, func() {
+		err = runTests([]string{"-o", output, "-post-hook", "exit 3", "-post-hook-required", "./testdata/simple"})
+	})
+	if err == nil || !strings.Contains(err.Error(), "-post-hook failed") {
+		t.Errorf("expected the hook to fail the run, got %v", err)
+	}
+}
+
+func TestMakeTempDir(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gocov")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	tmpDir, err := makeTempDir(dir, "ci")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := filepath.Join(dir, "gocov-ci"); tmpDir != expected {
+		t.Errorf("got temp directory %q, expected %q", tmpDir, expected)
+	}
+	// A second run with the same name must not share the directory.
+	if _, err := makeTempDir(dir, "ci"); err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Errorf("expected an error for an existing temp directory, got %v", err)
+	}
+	if _, err := makeTempDir(dir, "../ci"); err == nil {
+		t.Error("expected an error for a name that is a path")
+	}
+	random, err := makeTempDir(dir, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if random == tmpDir || !strings.HasPrefix(filepath.Base(random), "gocov") {
+		t.Errorf("unexpected random temp directory %q", random)
+	}
+}
@@ -0,0 +1,6 @@
+package args
+
+// Greeting returns a greeting for the environment.
+func Greeting(env string) string {
+	return "hello, " + env
+}
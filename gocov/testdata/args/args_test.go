@@ -0,0 +1,17 @@
+package args
+
+import (
+	"flag"
+	"testing"
+)
+
+var env = flag.String("env", "", "environment to greet")
+
+func TestGreeting(t *testing.T) {
+	if *env != "prod" {
+		t.Fatalf("got -env=%q, expected prod", *env)
+	}
+	if got := Greeting(*env); got != "hello, prod" {
+		t.Errorf("got %q", got)
+	}
+}
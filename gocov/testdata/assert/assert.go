@@ -0,0 +1,19 @@
+package assert
+
+// Int reports whether x holds an int using the two-value form of a
+// type assertion, which does not panic.
+func Int(x interface{}) (int, bool) {
+	v, ok := x.(int)
+	return v, ok
+}
+
+// MustInt uses the one-value form, which panics if x is not an int.
+func MustInt(x interface{}) int {
+	return x.(int)
+}
+
+// Truncate converts f to an int.
+func Truncate(f float64) int {
+	n := int(f)
+	return n
+}
@@ -0,0 +1,15 @@
+package assert
+
+import "testing"
+
+func TestAssert(t *testing.T) {
+	if _, ok := Int("not an int"); ok {
+		t.Error("a string is not an int")
+	}
+	if MustInt(1) != 1 {
+		t.Error("1 is not 1")
+	}
+	if Truncate(2.5) != 2 {
+		t.Error("2.5 does not truncate to 2")
+	}
+}
@@ -0,0 +1,13 @@
+package blank
+
+import "strconv"
+
+// Parse returns the integer in s, or zero if it is not an integer.
+func Parse(s string) int {
+	_ = len(s)
+	if _, err := strconv.Atoi(s); err != nil {
+		return 0
+	}
+	n, _ := strconv.Atoi(s)
+	return n
+}
@@ -0,0 +1,9 @@
+package blank
+
+import "testing"
+
+func TestParse(t *testing.T) {
+	if n := Parse("x"); n != 0 {
+		t.Fatalf("Parse(%q) = %d", "x", n)
+	}
+}
@@ -0,0 +1,13 @@
+//go:build cgo
+
+package cgo
+
+// Enabled reports whether the package was built with cgo.
+func Enabled() bool {
+	return WithCgo()
+}
+
+// WithCgo is only built when cgo is enabled.
+func WithCgo() bool {
+	return true
+}
@@ -0,0 +1,7 @@
+package cgo
+
+import "testing"
+
+func TestEnabled(t *testing.T) {
+	Enabled()
+}
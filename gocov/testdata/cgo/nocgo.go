@@ -0,0 +1,13 @@
+//go:build !cgo
+
+package cgo
+
+// Enabled reports whether the package was built with cgo.
+func Enabled() bool {
+	return WithoutCgo()
+}
+
+// WithoutCgo is only built when cgo is disabled.
+func WithoutCgo() bool {
+	return false
+}
@@ -0,0 +1,26 @@
+package complit
+
+import "strings"
+
+// commands maps names to closures, some of which are not called.
+var commands = map[string]func(string) string{
+	"upper": func(s string) string {
+		return strings.ToUpper(s)
+	},
+	"lower": func(s string) string {
+		return strings.ToLower(s)
+	},
+}
+
+// Run runs the named command on s.
+func Run(name, s string) string {
+	steps := []func(string) string{
+		func(s string) string {
+			return strings.TrimSpace(s)
+		},
+		func(s string) string {
+			return strings.Repeat(s, 2)
+		},
+	}
+	return commands[name](steps[0](s))
+}
@@ -0,0 +1,9 @@
+package complit
+
+import "testing"
+
+func TestRun(t *testing.T) {
+	if s := Run("upper", " a "); s != "A" {
+		t.Fatalf("Run(\"upper\", \" a \") = %q", s)
+	}
+}
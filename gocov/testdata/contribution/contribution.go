@@ -0,0 +1,15 @@
+package contribution
+
+// Classify is tested by both tests, but only TestNegative reaches
+// its negative branch.
+func Classify(n int) string {
+	if n < 0 {
+		return "negative"
+	}
+	return "positive"
+}
+
+// Negate is only called by TestNegative.
+func Negate(n int) int {
+	return -n
+}
@@ -0,0 +1,15 @@
+package contribution
+
+import "testing"
+
+func TestPositive(t *testing.T) {
+	if Classify(1) != "positive" {
+		t.Fatal("1 is not positive")
+	}
+}
+
+func TestNegative(t *testing.T) {
+	if Classify(Negate(1)) != "negative" {
+		t.Fatal("-1 is not negative")
+	}
+}
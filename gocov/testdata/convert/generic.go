@@ -0,0 +1,26 @@
+package convert
+
+type List[T any] struct {
+	items []T
+}
+
+func (l *List[T]) Push(v T) {
+	l.items = append(l.items, v)
+}
+
+type Pair[K comparable, V any] struct {
+	Key   K
+	Value V
+}
+
+func (p Pair[K, V]) Swap() Pair[K, V] {
+	return p
+}
+
+func Map[T, U any](in []T, f func(T) U) []U {
+	out := make([]U, 0, len(in))
+	for _, v := range in {
+		out = append(out, f(v))
+	}
+	return out
+}
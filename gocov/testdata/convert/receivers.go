@@ -0,0 +1,15 @@
+package convert
+
+type Value struct{}
+
+func (Value) Name() string {
+	return "value"
+}
+
+type Pointer struct {
+	Value
+}
+
+func (*Pointer) Name() string {
+	return "pointer"
+}
@@ -0,0 +1,9 @@
+package deferred
+
+// Recover panics, recording the recovered value in a deferred closure.
+func Recover() (recovered interface{}) {
+	defer func() {
+		recovered = recover()
+	}()
+	panic("deferred")
+}
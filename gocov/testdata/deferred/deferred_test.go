@@ -0,0 +1,9 @@
+package deferred
+
+import "testing"
+
+func TestRecover(t *testing.T) {
+	if Recover() != "deferred" {
+		t.Fatal("panic was not recovered")
+	}
+}
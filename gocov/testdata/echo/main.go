@@ -0,0 +1,13 @@
+// Command echo prints its arguments, quoted, to standard output.
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+func main() {
+	for _, arg := range os.Args[1:] {
+		fmt.Printf("%q\n", arg)
+	}
+}
@@ -0,0 +1,8 @@
+package env
+
+import "os"
+
+// Value returns the value of the GOCOV_TEST_VALUE environment variable.
+func Value() string {
+	return os.Getenv("GOCOV_TEST_VALUE")
+}
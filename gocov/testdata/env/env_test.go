@@ -0,0 +1,9 @@
+package env
+
+import "testing"
+
+func TestValue(t *testing.T) {
+	if value := Value(); value != "a=b c" {
+		t.Fatalf("GOCOV_TEST_VALUE=%q", value)
+	}
+}
@@ -0,0 +1,27 @@
+package errorpaths
+
+import (
+	"errors"
+	"strconv"
+)
+
+// Parse parses a non-negative integer.
+func Parse(s string) (int, error) {
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, errors.New("not a number: " + s)
+	}
+	if n < 0 {
+		return 0, errors.New("negative")
+	}
+	return n, nil
+}
+
+// Double parses a non-negative integer and doubles it.
+func Double(s string) (int, error) {
+	if n, parseErr := Parse(s); parseErr != nil {
+		return 0, parseErr
+	} else {
+		return 2 * n, nil
+	}
+}
@@ -0,0 +1,15 @@
+package errorpaths
+
+import "testing"
+
+func TestParse(t *testing.T) {
+	if _, err := Parse("x"); err == nil {
+		t.Error("expected an error")
+	}
+}
+
+func TestDouble(t *testing.T) {
+	if n, err := Double("2"); err != nil || n != 4 {
+		t.Errorf("Double(2) = %d, %v", n, err)
+	}
+}
@@ -0,0 +1,11 @@
+package example
+
+import "fmt"
+
+// Greet prints a greeting for name.
+func Greet(name string) {
+	if name == "" {
+		name = "world"
+	}
+	fmt.Printf("hello, %s\n", name)
+}
@@ -0,0 +1,17 @@
+package example
+
+func ExampleGreet() {
+	Greet("")
+	Greet("gocov")
+	// Output:
+	// hello, world
+	// hello, gocov
+}
+
+func ExampleGreet_unordered() {
+	Greet("b")
+	Greet("a")
+	// Unordered output:
+	// hello, a
+	// hello, b
+}
@@ -0,0 +1,11 @@
+package exit
+
+// Before is called before the test calling os.Exit does so.
+func Before() int {
+	return 1
+}
+
+// After would only be called by a test running after that one.
+func After() int {
+	return 2
+}
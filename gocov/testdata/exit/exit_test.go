@@ -0,0 +1,15 @@
+package exit
+
+import (
+	"os"
+	"testing"
+)
+
+func TestExit(t *testing.T) {
+	Before()
+	os.Exit(0)
+}
+
+func TestAfter(t *testing.T) {
+	After()
+}
@@ -0,0 +1,5 @@
+package failing
+
+func Broken() int {
+	return 1
+}
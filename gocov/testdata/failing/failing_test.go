@@ -0,0 +1,9 @@
+package failing
+
+import "testing"
+
+func TestBroken(t *testing.T) {
+	if Broken() != 2 {
+		t.Fatal("Broken is broken")
+	}
+}
@@ -0,0 +1,9 @@
+package fuzz
+
+// Trim removes the first byte of s, if any.
+func Trim(s string) string {
+	if s == "" {
+		return s
+	}
+	return s[1:]
+}
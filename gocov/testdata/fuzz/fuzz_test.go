@@ -0,0 +1,12 @@
+package fuzz
+
+import "testing"
+
+func FuzzTrim(f *testing.F) {
+	f.Add("abc")
+	f.Fuzz(func(t *testing.T, s string) {
+		if len(Trim(s)) > len(s) {
+			t.Fatal("Trim made the string longer")
+		}
+	})
+}
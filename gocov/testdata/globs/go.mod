@@ -0,0 +1,3 @@
+module example.com/globs
+
+go 1.20
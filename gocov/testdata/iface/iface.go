@@ -0,0 +1,57 @@
+package iface
+
+import (
+	"io"
+	"strings"
+)
+
+// Shape is implemented by Square and by pointers to Circle.
+type Shape interface {
+	Area() float64
+	Name() string
+}
+
+// Square is a square with sides of length Side.
+type Square struct {
+	Side float64
+}
+
+func (s Square) Area() float64 {
+	return s.Side * s.Side
+}
+
+func (s Square) Name() string {
+	return "square"
+}
+
+// Circle is a circle of radius Radius.
+type Circle struct {
+	Radius float64
+}
+
+func (c *Circle) Area() float64 {
+	return 3 * c.Radius * c.Radius
+}
+
+func (c *Circle) Name() string {
+	if c.Radius == 0 {
+		return "point"
+	}
+	return "circle"
+}
+
+// Perimeter is not a method of Shape.
+func (c *Circle) Perimeter() float64 {
+	return 6 * c.Radius
+}
+
+// upper implements io.Reader, reading its input in upper case.
+type upper struct {
+	r io.Reader
+}
+
+func (u upper) Read(p []byte) (int, error) {
+	n, err := u.r.Read(p)
+	copy(p, strings.ToUpper(string(p[:n])))
+	return n, err
+}
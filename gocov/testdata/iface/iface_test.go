@@ -0,0 +1,12 @@
+package iface
+
+import "testing"
+
+func TestShapes(t *testing.T) {
+	shapes := []Shape{Square{2}, &Circle{1}}
+	for _, shape := range shapes {
+		if shape.Area() <= 0 {
+			t.Errorf("%s has no area", shape.Name())
+		}
+	}
+}
@@ -0,0 +1,9 @@
+package linedirective
+
+//line parser.y:100
+func Parse(x int) int {
+	if x > 0 {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,9 @@
+package linedirective
+
+import "testing"
+
+func TestParse(t *testing.T) {
+	if Parse(1) != 1 {
+		t.Fatal("unexpected result")
+	}
+}
@@ -0,0 +1,10 @@
+package loud
+
+// Shout returns s repeated n times.
+func Shout(s string, n int) string {
+	var out string
+	for i := 0; i < n; i++ {
+		out += s
+	}
+	return out
+}
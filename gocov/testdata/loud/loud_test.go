@@ -0,0 +1,15 @@
+package loud
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestShout(t *testing.T) {
+	for i := 0; i < 100000; i++ {
+		fmt.Println("shouting", i)
+	}
+	if Shout("a", 3) != "aaaa" {
+		t.Fatal("Shout is too quiet")
+	}
+}
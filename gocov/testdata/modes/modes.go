@@ -0,0 +1,7 @@
+package modes
+
+// Hit does nothing, so that its statement is reached once each time
+// it is called.
+func Hit() int {
+	return 0
+}
@@ -0,0 +1,18 @@
+package modes
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestHit(t *testing.T) {
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			Hit()
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,17 @@
+package multiline
+
+import "strings"
+
+// Join joins a and b with a statement spanning several lines.
+func Join(a, b string) string {
+	s := strings.Join(
+		[]string{a, b},
+		",",
+	)
+	if s == "" {
+		return strings.Repeat(
+			"-", 3,
+		)
+	}
+	return s
+}
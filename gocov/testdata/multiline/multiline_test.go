@@ -0,0 +1,9 @@
+package multiline
+
+import "testing"
+
+func TestJoin(t *testing.T) {
+	if Join("a", "b") != "a,b" {
+		t.Error(`Join("a", "b") != "a,b"`)
+	}
+}
@@ -0,0 +1,9 @@
+package pertest
+
+func F() int {
+	return 1
+}
+
+func G() int {
+	return 2
+}
@@ -0,0 +1,11 @@
+package pertest
+
+import "testing"
+
+func TestF(t *testing.T) {
+	F()
+}
+
+func TestG(t *testing.T) {
+	G()
+}
@@ -0,0 +1,16 @@
+package platform
+
+import "runtime"
+
+// Separator returns the path separator of the platform.
+func Separator() string {
+	if runtime.GOOS == "windows" {
+		return `\`
+	}
+	return "/"
+}
+
+// Join joins two path elements.
+func Join(a, b string) string {
+	return a + Separator() + b
+}
@@ -0,0 +1,9 @@
+package platform
+
+import "testing"
+
+func TestJoin(t *testing.T) {
+	if Join("a", "b") == "" {
+		t.Fatal("empty path")
+	}
+}
@@ -0,0 +1,34 @@
+package recoverypaths
+
+import "fmt"
+
+// Safe calls f, reporting whether it panicked.
+func Safe(f func()) (panicked bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			panicked = true
+		}
+	}()
+	f()
+	return false
+}
+
+// Must calls f, returning any panic as an error.
+func Must(f func()) (err error) {
+	defer func() {
+		r := recover()
+		if r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	f()
+	return nil
+}
+
+// Quiet calls f, ignoring any panic.
+func Quiet(f func()) {
+	defer func() {
+		recover()
+	}()
+	f()
+}
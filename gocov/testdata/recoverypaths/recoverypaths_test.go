@@ -0,0 +1,19 @@
+package recoverypaths
+
+import "testing"
+
+func TestSafe(t *testing.T) {
+	if !Safe(func() { panic("boom") }) {
+		t.Error("expected a panic")
+	}
+}
+
+func TestMust(t *testing.T) {
+	if err := Must(func() {}); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestQuiet(t *testing.T) {
+	Quiet(func() {})
+}
@@ -0,0 +1,20 @@
+package reflect
+
+import "reflect"
+
+// Handler's methods are only called by Dispatch, through reflection.
+type Handler struct{}
+
+func (Handler) Greet(name string) string {
+	return "hello " + name
+}
+
+func (Handler) Ignored() string {
+	return "never called"
+}
+
+// Dispatch calls the named method of h with arg.
+func Dispatch(h interface{}, method, arg string) string {
+	out := reflect.ValueOf(h).MethodByName(method).Call([]reflect.Value{reflect.ValueOf(arg)})
+	return out[0].String()
+}
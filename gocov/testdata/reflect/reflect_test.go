@@ -0,0 +1,9 @@
+package reflect
+
+import "testing"
+
+func TestDispatch(t *testing.T) {
+	if s := Dispatch(Handler{}, "Greet", "gopher"); s != "hello gopher" {
+		t.Fatalf("Dispatch returned %q", s)
+	}
+}
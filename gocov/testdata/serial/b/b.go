@@ -0,0 +1,7 @@
+package b
+
+import "github.com/axw/gocov/gocov/testdata/serial"
+
+func Run() error {
+	return serial.Run("b")
+}
@@ -0,0 +1,15 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// Hello handles requests by greeting the named user.
+func Hello(w http.ResponseWriter, r *http.Request) {
+	name := r.URL.Query().Get("name")
+	if name == "" {
+		name = "world"
+	}
+	fmt.Fprintf(w, "hello, %s", name)
+}
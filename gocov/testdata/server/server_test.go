@@ -0,0 +1,40 @@
+package server
+
+import (
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"testing"
+)
+
+var addr string
+
+// TestMain serves Hello from a background goroutine while the
+// tests run.
+func TestMain(m *testing.M) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		panic(err)
+	}
+	addr = l.Addr().String()
+	go http.Serve(l, http.HandlerFunc(Hello))
+	code := m.Run()
+	l.Close()
+	os.Exit(code)
+}
+
+func TestHello(t *testing.T) {
+	resp, err := http.Get("http://" + addr + "/?name=gocov")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(body) != "hello, gocov" {
+		t.Fatalf("unexpected response %q", body)
+	}
+}
@@ -0,0 +1,12 @@
+package simple
+
+func Covered(x int) int {
+	if x > 0 {
+		return x
+	}
+	return -x
+}
+
+func Uncovered() string {
+	return "uncovered"
+}
@@ -0,0 +1,9 @@
+package simple
+
+import "testing"
+
+func TestCovered(t *testing.T) {
+	if Covered(1) != 1 {
+		t.Fatal("unexpected result")
+	}
+}
@@ -0,0 +1,21 @@
+package slow
+
+import (
+	"io/ioutil"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestWait(t *testing.T) {
+	// Record the process ID of the test binary, so that gocov's
+	// tests can check that it is killed.
+	if file := os.Getenv("GOCOV_TEST_PID"); file != "" {
+		pid := []byte(strconv.Itoa(os.Getpid()))
+		if err := ioutil.WriteFile(file, pid, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	Wait(time.Minute)
+}
@@ -0,0 +1,7 @@
+package sonarqube
+
+// Max returns the greater of a and b.
+func Max(a, b int) int {
+	if a > b { return a }
+	return b
+}
@@ -0,0 +1,15 @@
+package sonarqube
+
+import "testing"
+
+func TestAbs(t *testing.T) {
+	if Abs(1) != 1 {
+		t.Error("Abs(1) != 1")
+	}
+}
+
+func TestMax(t *testing.T) {
+	if Max(1, 2) != 2 {
+		t.Error("Max(1, 2) != 2")
+	}
+}
@@ -0,0 +1,31 @@
+package switches
+
+// Kind describes the dynamic type of x.
+func Kind(x interface{}) string {
+	switch v := x.(type) {
+	case int:
+		if v < 0 {
+			return "negative int"
+		}
+		return "int"
+	case string, []byte:
+		return "text"
+	case nil:
+		return "nil"
+	default:
+		_ = v
+		return "other"
+	}
+}
+
+// Color returns the hex code of a named color.
+func Color(name string) string {
+	switch name {
+	case "red":
+		return "#f00"
+	case "green", "lime":
+		return "#0f0"
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,21 @@
+package switches
+
+import "testing"
+
+func TestKind(t *testing.T) {
+	for x, kind := range map[interface{}]string{
+		1:    "int",
+		"a":  "text",
+		3.14: "other",
+	} {
+		if k := Kind(x); k != kind {
+			t.Errorf("Kind(%v) = %q, expected %q", x, k, kind)
+		}
+	}
+}
+
+func TestColor(t *testing.T) {
+	if Color("lime") != "#0f0" {
+		t.Error("unexpected color")
+	}
+}
@@ -0,0 +1,9 @@
+//go:build a
+// +build a
+
+package tags
+
+// A is only compiled with the "a" tag.
+func A() int {
+	return Common("a")
+}
@@ -0,0 +1,12 @@
+//go:build a
+// +build a
+
+package tags
+
+import "testing"
+
+func TestA(t *testing.T) {
+	if A() != 1 {
+		t.Fatal("A() != 1")
+	}
+}
@@ -0,0 +1,9 @@
+//go:build b
+// +build b
+
+package tags
+
+// B is only compiled with the "b" tag.
+func B() int {
+	return Common("b")
+}
@@ -0,0 +1,12 @@
+//go:build b
+// +build b
+
+package tags
+
+import "testing"
+
+func TestB(t *testing.T) {
+	if B() != 2 {
+		t.Fatal("B() != 2")
+	}
+}
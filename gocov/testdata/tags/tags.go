@@ -0,0 +1,9 @@
+package tags
+
+// Common is compiled with any tags.
+func Common(tag string) int {
+	if tag == "a" {
+		return 1
+	}
+	return 2
+}
@@ -0,0 +1,23 @@
+package varinit
+
+// Table is computed by a closure when the package is initialized.
+var Table = func() []int {
+	table := make([]int, 4)
+	for i := range table {
+		table[i] = i * i
+	}
+	return table
+}()
+
+// unused is never called.
+var unused = func() int {
+	return 1
+}
+
+// Square returns the square of n.
+func Square(n int) int {
+	if n < len(Table) {
+		return Table[n]
+	}
+	return n * n
+}
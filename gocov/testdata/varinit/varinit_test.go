@@ -0,0 +1,9 @@
+package varinit
+
+import "testing"
+
+func TestSquare(t *testing.T) {
+	if Square(3) != 9 {
+		t.Fatal("Square(3) != 9")
+	}
+}
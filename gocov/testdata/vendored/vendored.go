@@ -0,0 +1,5 @@
+package vendored
+
+func F() int {
+	return 1
+}
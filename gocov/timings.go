@@ -0,0 +1,83 @@
+// Copyright (c) 2026 The Gocov Authors.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+// timings records the time spent in each phase of gocov test, for
+// -timings. A nil *timings runs each phase without recording it.
+type timings struct {
+	mu     sync.Mutex
+	phases []phaseTiming
+}
+
+type phaseTiming struct {
+	phase    string
+	pkg      string
+	start    time.Time
+	duration time.Duration
+}
+
+// time runs f, recording the time it took as the named phase for
+// the package pkg, which may be empty.
+func (t *timings) time(phase, pkg string, f func() error) error {
+	if t == nil {
+		return f()
+	}
+	start := time.Now()
+	err := f()
+	t.mu.Lock()
+	t.phases = append(t.phases, phaseTiming{phase, pkg, start, time.Since(start)})
+	t.mu.Unlock()
+	return err
+}
+
+// write prints the recorded phases in the order they started,
+// followed by the total time from the start of the first phase to the
+// end of the last. With -parallel-packages, the tests of packages may
+// overlap, and so the total may be less than the sum of the phases.
+func (t *timings) write(w io.Writer) {
+	phases := append([]phaseTiming(nil), t.phases...)
+	sort.SliceStable(phases, func(i, j int) bool {
+		return phases[i].start.Before(phases[j].start)
+	})
+	tw := tabwriter.NewWriter(w, 0, 8, 1, ' ', 0)
+	fmt.Fprintln(tw, "PHASE\tPACKAGE\tTIME")
+	var first, last time.Time
+	for i, p := range phases {
+		fmt.Fprintf(tw, "%s\t%s\t%v\n", p.phase, p.pkg, p.duration.Round(time.Millisecond))
+		if end := p.start.Add(p.duration); i == 0 || end.After(last) {
+			last = end
+		}
+		if i == 0 {
+			first = p.start
+		}
+	}
+	fmt.Fprintf(tw, "total\t\t%v\n", last.Sub(first).Round(time.Millisecond))
+	tw.Flush()
+}
@@ -0,0 +1,148 @@
+// Copyright (c) 2026 The Gocov Authors.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package main
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// trendHeader is the first row of a -trend-file.
+var trendHeader = []string{"time", "coverage", "reached", "statements", "commit"}
+
+// appendTrend appends a row recording the total coverage of the report
+// at the time now, and the commit checked out, to the named CSV file,
+// creating it with a header row if it does not exist.
+func appendTrend(filename string, r *report, now time.Time, commit string) error {
+	file, err := os.OpenFile(filename, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	w := csv.NewWriter(file)
+	if info.Size() == 0 {
+		w.Write(trendHeader)
+	}
+	statements, reached := r.totals()
+	w.Write([]string{
+		now.UTC().Format(time.RFC3339),
+		strconv.FormatFloat(percent(reached, statements), 'f', 2, 64),
+		strconv.Itoa(reached),
+		strconv.Itoa(statements),
+		commit,
+	})
+	w.Flush()
+	if err := w.Error(); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// gitCommit returns the commit checked out in the working directory,
+// or "" if it is not within a git repository.
+func gitCommit() string {
+	output, err := exec.Command("git", "rev-parse", "HEAD").Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(output))
+}
+
+// sparks are the characters of a sparkline, from lowest to highest.
+var sparks = []rune("▁▂▃▄▅▆▇█")
+
+// sparkline returns a line of characters whose heights show values
+// between the least and greatest of them.
+func sparkline(values []float64) string {
+	if len(values) == 0 {
+		return ""
+	}
+	min, max := values[0], values[0]
+	for _, v := range values {
+		if v < min {
+			min = v
+		}
+		if v > max {
+			max = v
+		}
+	}
+	line := make([]rune, len(values))
+	for i, v := range values {
+		level := len(sparks) / 2
+		if max > min {
+			level = int((v - min) / (max - min) * float64(len(sparks)-1))
+		}
+		line[i] = sparks[level]
+	}
+	return string(line)
+}
+
+// printTrend prints a sparkline of the coverage recorded in the
+// named -trend-file, with the first and last coverage recorded.
+func printTrend(w io.Writer, filename string) error {
+	file, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	rows, err := csv.NewReader(file).ReadAll()
+	if err != nil {
+		return err
+	}
+	if len(rows) > 0 && rows[0][0] == trendHeader[0] {
+		rows = rows[1:]
+	}
+	if len(rows) == 0 {
+		return fmt.Errorf("%s: no coverage recorded", filename)
+	}
+	values := make([]float64, len(rows))
+	for i, row := range rows {
+		if len(row) < 2 {
+			return fmt.Errorf("%s: row %d: missing coverage", filename, i+2)
+		}
+		if values[i], err = strconv.ParseFloat(row[1], 64); err != nil {
+			return fmt.Errorf("%s: row %d: %v", filename, i+2, err)
+		}
+	}
+	_, err = fmt.Fprintf(w, "%s %.2f%% -> %.2f%% (%d runs)\n",
+		sparkline(values), values[0], values[len(values)-1], len(values))
+	return err
+}
+
+// trendCoverage implements "gocov trend <file>".
+func trendCoverage(args []string) error {
+	if len(args) != 1 {
+		return errors.New("usage: gocov trend <trend-file>")
+	}
+	return printTrend(os.Stdout, args[0])
+}
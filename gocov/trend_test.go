@@ -0,0 +1,105 @@
+// Copyright (c) 2026 The Gocov Authors.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to
+// deal in the Software without restriction, including without limitation the
+// rights to use, copy, modify, merge, publish, distribute, sublicense, and/or
+// sell copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING
+// FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS
+// IN THE SOFTWARE.
+
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/axw/gocov"
+)
+
+func TestReportTrendFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gocov")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	trendFile := filepath.Join(dir, "coverage-trend.csv")
+
+	// Each report appends a row.
+	for _, reached := range [][]int64{{1, 0, 0, 0}, {1, 1, 1, 0}} {
+		data, err := marshalJson([]*gocov.Package{{
+			Name:      "example.com/a",
+			Functions: []*gocov.Function{newFunction("F", "a.go", reached...)},
+		}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		input := filepath.Join(dir, "coverage.json")
+		if err := ioutil.WriteFile(input, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+		if rc, _, stderr := runReport(t, "-trend-file", trendFile, input); rc != 0 {
+			t.Fatalf("report failed: %s", stderr)
+		}
+	}
+
+	file, err := os.Open(trendFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	rows, err := csv.NewReader(file).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 3 || !reflect.DeepEqual(rows[0], trendHeader) {
+		t.Fatalf("expected a header and 2 rows, got %q", rows)
+	}
+	commit := gitCommit()
+	for i, expect := range [][]string{{"25.00", "1", "4", commit}, {"75.00", "3", "4", commit}} {
+		if got := rows[i+1][1:]; !reflect.DeepEqual(got, expect) {
+			t.Errorf("row %d: got %q, expected %q", i+1, got, expect)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := printTrend(&buf, trendFile); err != nil {
+		t.Fatal(err)
+	}
+	if expect := "▁█ 25.00% -> 75.00% (2 runs)\n"; buf.String() != expect {
+		t.Errorf("got %q, expected %q", buf.String(), expect)
+	}
+}
+
+func TestSparkline(t *testing.T) {
+	tests := []struct {
+		values []float64
+		expect string
+	}{
+		{nil, ""},
+		{[]float64{50}, "▅"},
+		{[]float64{50, 50}, "▅▅"},
+		{[]float64{0, 50, 100}, "▁▄█"},
+		{[]float64{80, 70, 90}, "▄▁█"},
+	}
+	for _, test := range tests {
+		if got := sparkline(test.values); got != test.expect {
+			t.Errorf("sparkline(%v) = %q, expected %q", test.values, got, test.expect)
+		}
+	}
+}